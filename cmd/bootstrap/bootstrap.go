@@ -0,0 +1,110 @@
+package bootstrap
+
+import (
+	"golek_posts_service/cmd/msg_broker"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/database"
+	"golek_posts_service/pkg/repositories"
+	"os"
+	"strconv"
+)
+
+// GetEnv returns the environment variable or the fallback when it is empty
+func GetEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// NewDatabase connects to the database configured in the environment
+func NewDatabase() *database.Database {
+
+	db := database.Database{
+		DbName:             os.Getenv("DB_NAME"),
+		DbCollection:       os.Getenv("DB_COLLECTION"),
+		DbClaimCollection:  GetEnv("DB_CLAIM_COLLECTION", "claims"),
+		DbAuditCollection:  GetEnv("DB_AUDIT_COLLECTION", "post_audits"),
+		DbOutboxCollection: GetEnv("DB_OUTBOX_COLLECTION", "post_outbox"),
+		DbHost:             os.Getenv("DB_HOST"),
+		DbPort:             os.Getenv("DB_PORT_IN"),
+		DbUsername:         os.Getenv("DB_USERNAME"),
+		DBPassword:         os.Getenv("DB_PASSWORD"),
+	}
+	db.Prepare()
+
+	return &db
+}
+
+// NewStorage creates and connects the storage backend selected by STORAGE_DRIVER
+func NewStorage() (contracts.StorageRepository, error) {
+
+	uploadMaxSizeMB, _ := strconv.Atoi(os.Getenv("UPLOAD_MAX_SIZE_MB"))
+	imageMaxPixels, _ := strconv.ParseInt(os.Getenv("IMAGE_MAX_PIXELS"), 10, 64)
+	uploadValidator := repositories.NewUploadValidator([]string{"image/jpeg", "image/png"}, int64(uploadMaxSizeMB*1024*1024), imageMaxPixels)
+	storageBucket := GetEnv("STORAGE_BUCKET", "ayocode1-bucker")
+
+	var storage contracts.StorageRepository
+	var err error
+	switch driver := GetEnv("STORAGE_DRIVER", "gcs"); driver {
+	case "gcs":
+		storage = repositories.NewGCStorageService(storageBucket, GetEnv("STORAGE_KEY_PATH", "keys.json"), uploadValidator)
+	case "local":
+		storage, err = repositories.NewLocalStorageService(
+			GetEnv("STORAGE_LOCAL_DIR", "storage"),
+			GetEnv("STORAGE_PUBLIC_URL", "http://localhost:"+GetEnv("APP_PORT", "8080")),
+			storageBucket,
+			os.Getenv("STORAGE_SIGNING_SECRET"),
+			uploadValidator,
+		)
+		if err != nil {
+			return nil, err
+		}
+	case "s3":
+		storage = repositories.NewS3Repository(
+			os.Getenv("AWS_ACCESS_KEY_ID"),
+			os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			os.Getenv("AWS_BUCKET_NAME"),
+			os.Getenv("AWS_BUCKET_REGION"),
+			uploadValidator,
+			0,
+			3,
+		)
+	case "memory":
+		storage = repositories.NewMemoryStorageService(storageBucket, uploadValidator)
+	default:
+		panic("unknown STORAGE_DRIVER " + driver)
+	}
+
+	return storage, storage.Connect()
+}
+
+// NewMessageQueue creates the publisher selected by MESSAGE_BROKER and starts connecting it
+func NewMessageQueue() contracts.MessageQueue {
+
+	var messageQueue contracts.MessageQueue
+	switch driver := GetEnv("MESSAGE_BROKER", "rabbitmq"); driver {
+	case "rabbitmq":
+		messageQueue = msg_broker.NewMQPublisher(RabbitMQURL())
+	case "nats":
+		messageQueue = msg_broker.NewNATSPublisher(GetEnv("NATS_URL", "nats://localhost:4222"), GetEnv("NATS_STREAM", "POSTS"))
+	case "log":
+		messageQueue = msg_broker.NewLogPublisher()
+	case "memory":
+		messageQueue = msg_broker.NewMemoryBroker()
+	default:
+		panic("unknown MESSAGE_BROKER " + driver)
+	}
+
+	messageQueue.Setup()
+	return messageQueue
+}
+
+func RabbitMQURL() string {
+	return msg_broker.URL(
+		os.Getenv("RABBITMQ_USER"),
+		os.Getenv("RABBITMQ_PASSWORD"),
+		os.Getenv("RABBITMQ_HOST"),
+		os.Getenv("RABBITMQ_PORT"),
+	)
+}
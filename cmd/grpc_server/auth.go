@@ -0,0 +1,95 @@
+package grpc_server
+
+import (
+	"context"
+	"golek_posts_service/pkg/http/middleware"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods of the post service can be called by other services without an authenticated user.
+// The health and reflection services are always public
+var publicMethods = map[string]bool{
+	//Fetch is the service to service lookup of bookmarked posts, done on behalf of no particular user
+	"/model.PostService/Fetch": true,
+}
+
+// AuthInterceptor maps the x-user-* metadata, the counterpart of the X-User-* headers
+// checked by the REST middleware, onto an AuthenticatedRequest stored in the context
+func AuthInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+	ctx, err := authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// AuthStreamInterceptor is the AuthInterceptor of streaming calls
+func AuthStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	ctx, err := authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+func authenticate(ctx context.Context, method string) (context.Context, error) {
+
+	authenticated, ok := authenticatedRequest(ctx)
+	if ok {
+		return context.WithValue(ctx, "authenticatedRequest", authenticated), nil
+	}
+
+	if strings.HasPrefix(method, "/model.PostService/") && !publicMethods[method] {
+		return nil, status.Error(codes.Unauthenticated, "x-user-id, x-user-role and x-user-permission metadata are required")
+	}
+
+	return ctx, nil
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (c *contextStream) Context() context.Context {
+	return c.ctx
+}
+
+func authenticatedRequest(ctx context.Context) (*middleware.AuthenticatedRequest, bool) {
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	get := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	authenticated := &middleware.AuthenticatedRequest{
+		UserID:      get("x-user-id"),
+		Role:        get("x-user-role"),
+		Permissions: get("x-user-permission"),
+		Username:    get("x-user-name"),
+		UserMajor:   get("x-user-major"),
+	}
+
+	if authenticated.UserID == "" || authenticated.Role == "" || authenticated.Permissions == "" {
+		return nil, false
+	}
+
+	return authenticated, true
+}
@@ -0,0 +1,60 @@
+package grpc_server
+
+import (
+	"golek_posts_service/pkg/models"
+	ps "golek_posts_service/pkg/models/proto_schema"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toProtoPost(post models.Post) *ps.Post {
+
+	characteristics := make([]*ps.Characteristic, len(post.Characteristics))
+	for i, c := range post.Characteristics {
+		characteristics[i] = &ps.Characteristic{Title: c.Title}
+	}
+
+	images := make([]*ps.PostImage, len(post.Images))
+	for i, image := range post.Images {
+		images[i] = &ps.PostImage{
+			Id:        image.ID,
+			Url:       image.URL,
+			MediumUrl: image.MediumURL,
+			SmallUrl:  image.SmallURL,
+			Order:     int32(image.Order),
+		}
+	}
+
+	return &ps.Post{
+		Id:              post.ID.Hex(),
+		Name:            post.Title,
+		ImageUrl:        post.ImageURL,
+		Title:           post.Title,
+		UserId:          post.UserID,
+		Type:            string(post.Type),
+		Status:          string(post.CurrentStatus()),
+		Place:           post.Place,
+		Description:     post.Description,
+		Characteristics: characteristics,
+		User: &ps.UserInfo{
+			Username:  post.User.Username,
+			UserMajor: post.User.UserMajor,
+		},
+		Images:         images,
+		CoverImageId:   post.CoverImageID,
+		MediumImageUrl: post.MediumImageURL,
+		SmallImageUrl:  post.SmallImageURL,
+		IsReturned:     post.IsReturned,
+		ReturnedTo:     post.ReturnedTo,
+		CreatedAt:      timestamp(post.CreatedAt),
+		UpdatedAt:      timestamp(post.UpdatedAt),
+	}
+}
+
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
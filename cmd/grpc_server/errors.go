@@ -0,0 +1,56 @@
+package grpc_server
+
+import (
+	"errors"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// statusCodes mirrors the http status the REST controllers answer with for each operation status
+var statusCodes = map[status.PostOperationStatus]codes.Code{
+	status.OperationUnauthorized:       codes.Unauthenticated,
+	status.OperationForbidden:          codes.PermissionDenied,
+	status.ClaimNotApproved:            codes.PermissionDenied,
+	status.PostInvalidStateTransition:  codes.FailedPrecondition,
+	status.PostImagesInvalid:           codes.InvalidArgument,
+	status.PostImagesConflict:          codes.Aborted,
+	status.PostUploadTooLarge:          codes.InvalidArgument,
+	status.PostUploadInvalid:           codes.InvalidArgument,
+	status.PostUploadMissing:           codes.FailedPrecondition,
+	status.PostValidationTokenExpired:  codes.FailedPrecondition,
+	status.PostValidationTokenReplayed: codes.Aborted,
+	status.PostValidationTokenTampered: codes.InvalidArgument,
+}
+
+// statusError converts the result of a service call into a grpc status error
+func statusError(operation string, opStatus status.PostOperationStatus, err error) error {
+
+	if err == nil {
+		return nil
+	}
+
+	code, ok := statusCodes[opStatus]
+	if !ok {
+		code = errorCode(err)
+	}
+
+	return grpcstatus.Error(code, operation+" "+err.Error())
+}
+
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return codes.NotFound
+	case errors.Is(err, primitive.ErrInvalidHex), errors.Is(err, contracts.ErrInvalidResumeToken),
+		errors.Is(err, contracts.ErrTooManyPostIDs):
+		return codes.InvalidArgument
+	case errors.Is(err, contracts.ErrResumeTokenExpired):
+		return codes.OutOfRange
+	}
+	return codes.Internal
+}
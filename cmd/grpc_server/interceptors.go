@@ -0,0 +1,78 @@
+package grpc_server
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LoggingInterceptor logs every call with its status code and duration
+func LoggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(info.FullMethod, start, err)
+
+	return resp, err
+}
+
+func LoggingStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	start := time.Now()
+	err := handler(srv, stream)
+	logCall(info.FullMethod, start, err)
+
+	return err
+}
+
+func logCall(method string, start time.Time, err error) {
+	log.Printf("[GRPC] %-16s | %13v | %s", status.Code(err), time.Since(start), method)
+}
+
+// RecoveryInterceptor turns a panicking handler into an Internal error instead of crashing the service
+func RecoveryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+func RecoveryStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+
+	return handler(srv, stream)
+}
+
+func recovered(method string, r any) error {
+	log.Printf("[GRPC] panic in %s: %v\n%s", method, r, debug.Stack())
+	return status.Error(codes.Internal, "internal error")
+}
+
+// DeadlineInterceptor gives calls without a deadline the default one. Streams are left
+// alone, a watch is meant to stay open
+func DeadlineInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		return handler(ctx, req)
+	}
+}
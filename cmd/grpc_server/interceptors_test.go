@@ -0,0 +1,100 @@
+package grpc_server
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	ps "golek_posts_service/pkg/models/proto_schema"
+	"golek_posts_service/pkg/services"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestInterceptors(t *testing.T) {
+
+	postService := &stubPostService{}
+	var service contracts.PostServiceContract = postService
+	urlResolver := services.NewURLResolver("", "", nil, 0)
+	server := New(&service, &urlResolver, time.Second)
+
+	listener := bufconn.Listen(1024 * 1024)
+	go server.server.Serve(listener)
+	defer server.server.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	client := ps.NewPostServiceClient(conn)
+	authenticated := metadata.AppendToOutgoingContext(context.Background(),
+		"x-user-id", "7", "x-user-role", "user", "x-user-permission", "r",
+	)
+
+	t.Run("Recovers Panics", func(t *testing.T) {
+		_, err := client.Get(authenticated, &ps.GetPostRequest{Id: "panic"})
+		assert.Equal(t, codes.Internal, grpcstatus.Code(err))
+
+		//The server keeps serving
+		_, err = client.Get(authenticated, &ps.GetPostRequest{Id: "missing"})
+		assert.Equal(t, codes.NotFound, grpcstatus.Code(err))
+	})
+
+	t.Run("Applies Default Deadline", func(t *testing.T) {
+		postService.deadline = false
+		_, _ = client.Get(authenticated, &ps.GetPostRequest{Id: "missing"})
+		assert.True(t, postService.deadline)
+	})
+
+	t.Run("Authenticates Streams", func(t *testing.T) {
+		stream, err := client.WatchPosts(authenticated, &ps.WatchPostsRequest{UserId: 7})
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.OutOfRange, grpcstatus.Code(err))
+		assert.Equal(t, "7", postService.watchAuth.UserID)
+
+		_, err = client.Delete(context.Background(), &ps.DeletePostRequest{Id: "id"})
+		assert.Equal(t, codes.Unauthenticated, grpcstatus.Code(err))
+
+		stream, err = client.WatchPosts(context.Background(), &ps.WatchPostsRequest{UserId: 7})
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unauthenticated, grpcstatus.Code(err))
+	})
+
+	t.Run("Serves Health", func(t *testing.T) {
+		health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: "model.PostService"})
+		assert.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, health.Status)
+	})
+
+	t.Run("Serves Reflection", func(t *testing.T) {
+		info, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+		assert.NoError(t, err)
+		assert.NoError(t, info.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		}))
+
+		response, err := info.Recv()
+		assert.NoError(t, err)
+
+		names := make([]string, 0)
+		for _, service := range response.GetListServicesResponse().GetService() {
+			names = append(names, service.Name)
+		}
+		assert.Contains(t, names, "model.PostService")
+		assert.Contains(t, names, "grpc.health.v1.Health")
+	})
+}
@@ -3,53 +3,365 @@ package grpc_server
 import (
 	"context"
 	"fmt"
+	"golek_posts_service/cmd/bootstrap"
 	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/requests"
+	"golek_posts_service/pkg/models"
 	ps "golek_posts_service/pkg/models/proto_schema"
-	"google.golang.org/grpc"
 	"log"
 	"net"
 	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type GRPCPostServer struct {
 	ps.UnimplementedPostServiceServer
 	postService contracts.PostServiceContract
+	urlResolver contracts.URLResolverContract
+	server      *grpc.Server
+	health      *health.Server
+	//stopping is closed on shutdown, watches end right away instead of holding up the drain
+	stopping     chan struct{}
+	stoppingOnce sync.Once
 }
 
+// Fetch loads the posts in the requested order and reports the ids which could not be
+// returned, so callers can drop their stale references
 func (s *GRPCPostServer) Fetch(ctx context.Context, IDs *ps.PostIDs) (*ps.Posts, error) {
 
-	posts := make([]*ps.Post, 0)
+	posts, err := s.postService.FindByIDs(ctx, IDs.Id)
+	if err != nil {
+		return nil, grpcstatus.Error(errorCode(err), "PostService FindByIDs "+err.Error())
+	}
+
+	response := &ps.Posts{List: make([]*ps.Post, len(posts))}
+
+	found := make(map[primitive.ObjectID]bool, len(posts))
+	for i, post := range posts {
+		response.List[i] = s.toProto(post)
+		found[post.ID] = true
+	}
 
+	reported := make(map[string]bool)
 	for _, id := range IDs.Id {
-		post, err := s.postService.FindById(ctx, id)
+		if reported[id] {
+			continue
+		}
+		reported[id] = true
+
+		objectID, err := primitive.ObjectIDFromHex(id)
 		if err != nil {
-			log.Printf("RPC SERVER, Fetching Document %v Error: %v", IDs.Id, err.Error())
-		} else {
-			posts = append(posts, &ps.Post{
-				Id:       post.ID.Hex(),
-				Name:     post.Title,
-				ImageUrl: post.ImageURL,
-			})
+			response.Invalid = append(response.Invalid, id)
+		} else if !found[objectID] {
+			response.NotFound = append(response.NotFound, id)
+		}
+	}
+
+	return response, nil
+}
+
+func (s *GRPCPostServer) Get(ctx context.Context, req *ps.GetPostRequest) (*ps.Post, error) {
+
+	post, err := s.postService.FindById(ctx, req.Id)
+	if err != nil {
+		return nil, grpcstatus.Error(errorCode(err), err.Error())
+	}
+
+	return s.toProto(post), nil
+}
+
+func (s *GRPCPostServer) List(ctx context.Context, req *ps.ListPostsRequest) (*ps.PaginatedPosts, error) {
+
+	paginate := pagination(req.Page, req.Limit)
+
+	filter := map[string]any{"is_returned": req.GetReturned()}
+	if req.UserId != 0 {
+		filter["user_id"] = req.UserId
+	}
+
+	//An explicit lifecycle status replaces the default returned filter
+	if req.Status != "" {
+		if !models.PostStatus(req.Status).IsValid() {
+			return nil, grpcstatus.Error(codes.InvalidArgument, "status "+req.Status+" is not a valid post status")
+		}
+		filter["status"] = req.Status
+		if req.Returned == nil {
+			delete(filter, "is_returned")
+		}
+	}
+
+	if req.Type != "" {
+		if req.Type != string(models.PostTypeLost) && req.Type != string(models.PostTypeFound) {
+			return nil, grpcstatus.Error(codes.InvalidArgument, "type must be lost or found")
+		}
+		filter["type"] = req.Type
+	}
+
+	posts, err := s.postService.Fetch(ctx, paginate, filter)
+	if err != nil {
+		return nil, grpcstatus.Error(codes.Internal, "PostService Fetch "+err.Error())
+	}
+
+	return s.toPaginated(posts, paginate), nil
+}
+
+func (s *GRPCPostServer) Search(ctx context.Context, req *ps.SearchPostsRequest) (*ps.PaginatedPosts, error) {
+
+	paginate := pagination(req.Page, req.Limit)
+
+	posts, err := s.postService.Search(ctx, req.Keyword, paginate)
+	if err != nil {
+		return nil, grpcstatus.Error(codes.Internal, "PostService Search "+err.Error())
+	}
+
+	return s.toPaginated(posts, paginate), nil
+}
+
+func (s *GRPCPostServer) Create(ctx context.Context, req *ps.CreatePostRequest) (*ps.Post, error) {
+
+	if req.Title == "" || req.Place == "" || len(req.Characteristics) == 0 {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "title, place and characteristics are required")
+	}
+	if req.Type != "" && req.Type != string(models.PostTypeLost) && req.Type != string(models.PostTypeFound) {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "type must be lost or found")
+	}
+
+	createdPost, opStatus, err := s.postService.Create(ctx, requests.CreatePostRequest{
+		Title:           req.Title,
+		Type:            req.Type,
+		UploadKeys:      req.UploadKeys,
+		Place:           req.Place,
+		Description:     req.Description,
+		Characteristics: characteristicRequests(req.Characteristics),
+	})
+	if err != nil {
+		return nil, statusError("PostService Create", opStatus, err)
+	}
+
+	return s.toProto(createdPost), nil
+}
+
+func (s *GRPCPostServer) Update(ctx context.Context, req *ps.UpdatePostRequest) (*ps.Post, error) {
+
+	if req.Title == "" || req.Place == "" || len(req.Characteristics) == 0 {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "title, place and characteristics are required")
+	}
+
+	updatedPost, opStatus, err := s.postService.Update(ctx, req.Id, requests.UpdatePostRequest{
+		Title:           req.Title,
+		Place:           req.Place,
+		Description:     req.Description,
+		Characteristics: characteristicRequests(req.Characteristics),
+	})
+	if err != nil {
+		return nil, statusError("PostService Update", opStatus, err)
+	}
+
+	return s.toProto(updatedPost), nil
+}
+
+func (s *GRPCPostServer) Delete(ctx context.Context, req *ps.DeletePostRequest) (*ps.DeletePostResponse, error) {
+
+	opStatus, err := s.postService.Delete(ctx, req.Id)
+	if err != nil {
+		return nil, statusError("PostService Delete", opStatus, err)
+	}
+
+	return &ps.DeletePostResponse{}, nil
+}
+
+func (s *GRPCPostServer) RequestValidateOwner(ctx context.Context, req *ps.RequestValidateOwnerRequest) (*ps.RequestValidateOwnerResponse, error) {
+
+	if req.PostId == "" {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "post_id is required")
+	}
+
+	qrCodeUrl, opStatus, err := s.postService.RequestValidateOwner(ctx, req.PostId)
+	if err != nil {
+		return nil, statusError("Request Validate Owner", opStatus, err)
+	}
+
+	return &ps.RequestValidateOwnerResponse{
+		QrCodeUrl:       qrCodeUrl,
+		AlreadyReturned: opStatus == status.PostAlreadyReturned,
+	}, nil
+}
+
+func (s *GRPCPostServer) ValidateOwner(ctx context.Context, req *ps.ValidateOwnerRequest) (*ps.ValidateOwnerResponse, error) {
+
+	if req.PostId == "" || req.Token == "" {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "post_id and token are required")
+	}
+
+	opStatus, err := s.postService.ValidateOwner(ctx, requests.ValidateItemOwnerRequest{
+		PostID: req.PostId,
+		Token:  req.Token,
+	})
+	if err != nil {
+		return nil, statusError("Validate Owner", opStatus, err)
+	}
+
+	return &ps.ValidateOwnerResponse{AlreadyReturned: opStatus == status.PostAlreadyReturned}, nil
+}
+
+// WatchPosts streams the post changes until the client goes away. A client reconnects
+// with the resume token of the last change it received to not miss any change
+func (s *GRPCPostServer) WatchPosts(req *ps.WatchPostsRequest, stream ps.PostService_WatchPostsServer) error {
+
+	for _, id := range req.PostIds {
+		if !primitive.IsValidObjectID(id) {
+			return grpcstatus.Error(codes.InvalidArgument, id+" is not a valid post id")
 		}
+	}
 
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopping:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	filter := contracts.PostWatchFilter{PostIDs: req.PostIds, UserID: req.UserId}
+
+	err := s.postService.Watch(ctx, filter, req.ResumeToken, func(change contracts.PostChange) error {
+		return stream.Send(s.toProtoChange(change))
+	})
+	if ctxErr := stream.Context().Err(); ctxErr != nil {
+		return grpcstatus.FromContextError(ctxErr).Err()
+	}
+	if ctx.Err() != nil {
+		return grpcstatus.Error(codes.Unavailable, "server is shutting down, resume the watch")
+	}
+	if _, ok := grpcstatus.FromError(err); ok {
+		return err
 	}
-	return &ps.Posts{List: posts}, nil
+
+	return grpcstatus.Error(errorCode(err), "PostService Watch "+err.Error())
 }
 
-func (s *GRPCPostServer) Run() {
-	srv := grpc.NewServer()
-	ps.RegisterPostServiceServer(srv, s)
+// Run listens on RPC_HOST:RPC_PORT and serves until Shutdown is called
+func (s *GRPCPostServer) Run() error {
 
-	l, err := net.Listen("tcp", fmt.Sprintf("%v:%v", os.Getenv("RPC_HOST"), os.Getenv("RPC_PORT")))
+	port := bootstrap.GetEnv("RPC_PORT", "6060")
+	l, err := net.Listen("tcp", fmt.Sprintf("%v:%v", os.Getenv("RPC_HOST"), port))
 	if err != nil {
-		log.Fatalf("could not listen to %s: %v", os.Getenv("RPC_PORT"), err)
+		return fmt.Errorf("could not listen to %s: %w", port, err)
+	}
+
+	log.Println("RPC listening and serving TCP on", port)
+	return s.server.Serve(l)
+}
+
+// Shutdown ends the watches and waits for the pending calls to finish, they are cancelled once the context is done
+func (s *GRPCPostServer) Shutdown(ctx context.Context) error {
+
+	s.stoppingOnce.Do(func() { close(s.stopping) })
+
+	//Load balancers stop routing new calls while the pending ones drain
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// toProto resolves the image urls of the post and converts it to its grpc message
+func (s *GRPCPostServer) toProto(post models.Post) *ps.Post {
+	return toProtoPost(s.urlResolver.ResolvePost(post))
+}
+
+func (s *GRPCPostServer) toProtoChange(change contracts.PostChange) *ps.PostChange {
+
+	converted := &ps.PostChange{
+		Type:        string(change.Type),
+		PostId:      change.PostID,
+		ResumeToken: change.ResumeToken,
+		ChangedAt:   timestamppb.New(change.ChangedAt),
+	}
+	if change.Post != nil {
+		converted.Post = s.toProto(*change.Post)
 	}
 
-	log.Println("RPC listening and serving TCP on", os.Getenv("RPC_PORT"))
-	log.Fatal(srv.Serve(l))
+	return converted
 }
 
-func New(postService *contracts.PostServiceContract) *GRPCPostServer {
+func (s *GRPCPostServer) toPaginated(posts []models.Post, paginate models.Pagination) *ps.PaginatedPosts {
+
+	list := make([]*ps.Post, len(posts))
+	for i, post := range posts {
+		list[i] = s.toProto(post)
+	}
+
+	return &ps.PaginatedPosts{List: list, Page: paginate.Page, Limit: paginate.PerPage}
+}
+
+// pagination applies the defaults of the REST endpoints, the first page of 10 posts
+func pagination(page int64, limit int64) models.Pagination {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	return models.Pagination{Page: page, PerPage: limit}
+}
+
+func characteristicRequests(characteristics []*ps.Characteristic) []requests.PostCharacteristicRequest {
+	converted := make([]requests.PostCharacteristicRequest, len(characteristics))
+	for i, c := range characteristics {
+		converted[i] = requests.PostCharacteristicRequest{Title: c.Title}
+	}
+	return converted
+}
+
+// New builds the server with the post, health and reflection services. Unary calls
+// without a deadline get defaultTimeout
+func New(postService *contracts.PostServiceContract, urlResolver *contracts.URLResolverContract, defaultTimeout time.Duration) *GRPCPostServer {
+
+	if defaultTimeout <= 0 {
+		defaultTimeout = 10 * time.Second
+	}
+
+	s := &GRPCPostServer{
+		postService: *postService,
+		urlResolver: *urlResolver,
+		health:      health.NewServer(),
+		stopping:    make(chan struct{}),
+	}
+
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(LoggingInterceptor, RecoveryInterceptor, DeadlineInterceptor(defaultTimeout), AuthInterceptor),
+		grpc.ChainStreamInterceptor(LoggingStreamInterceptor, RecoveryStreamInterceptor, AuthStreamInterceptor),
+	)
+	ps.RegisterPostServiceServer(s.server, s)
+	healthpb.RegisterHealthServer(s.server, s.health)
+	reflection.Register(s.server)
+
+	s.health.SetServingStatus(ps.PostService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
 
-	return &GRPCPostServer{postService: *postService}
+	return s
 }
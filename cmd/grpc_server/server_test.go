@@ -0,0 +1,226 @@
+package grpc_server
+
+import (
+	"context"
+	"errors"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/models"
+	ps "golek_posts_service/pkg/models/proto_schema"
+	"golek_posts_service/pkg/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// stubPostService records the calls the grpc server makes to the post service
+type stubPostService struct {
+	contracts.PostServiceContract
+	filter    map[string]any
+	deleteErr error
+	deleteOp  status.PostOperationStatus
+	existing  []models.Post
+
+	watchFilter contracts.PostWatchFilter
+	watchAuth   *middleware.AuthenticatedRequest
+	changes     []contracts.PostChange
+	deadline    bool
+}
+
+// FindById panics for the "panic" id and records whether the call had a deadline
+func (s *stubPostService) FindById(ctx context.Context, postID string) (models.Post, error) {
+	if postID == "panic" {
+		panic("nil map")
+	}
+	_, s.deadline = ctx.Deadline()
+	return models.Post{}, mongo.ErrNoDocuments
+}
+
+// watchStream collects the changes sent to a WatchPosts client
+type watchStream struct {
+	grpc.ServerStream
+	sent []*ps.PostChange
+}
+
+func (w *watchStream) Context() context.Context {
+	return context.Background()
+}
+
+func (w *watchStream) Send(change *ps.PostChange) error {
+	w.sent = append(w.sent, change)
+	return nil
+}
+
+func (s *stubPostService) Fetch(ctx context.Context, pagination models.Pagination, filter map[string]any) ([]models.Post, error) {
+	s.filter = filter
+	return []models.Post{{ID: primitive.NewObjectID(), Title: "Wallet"}}, nil
+}
+
+// FindByIDs returns the posts of the ids among existing, in the requested order
+func (s *stubPostService) FindByIDs(ctx context.Context, postIDs []string) ([]models.Post, error) {
+	if len(postIDs) > contracts.MaxPostIDs {
+		return nil, contracts.ErrTooManyPostIDs
+	}
+	posts := make([]models.Post, 0)
+	for _, id := range postIDs {
+		for _, post := range s.existing {
+			if post.ID.Hex() == id {
+				posts = append(posts, post)
+			}
+		}
+	}
+	return posts, nil
+}
+
+// Watch replays the changes then fails like an expired resume token would
+func (s *stubPostService) Watch(ctx context.Context, filter contracts.PostWatchFilter, resumeToken string, fn func(change contracts.PostChange) error) error {
+	s.watchFilter = filter
+	s.watchAuth, _ = ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest)
+	for _, change := range s.changes {
+		if err := fn(change); err != nil {
+			return err
+		}
+	}
+	return contracts.ErrResumeTokenExpired
+}
+
+func (s *stubPostService) Delete(ctx context.Context, postID string) (status.PostOperationStatus, error) {
+	return s.deleteOp, s.deleteErr
+}
+
+func TestGRPCPostServer(t *testing.T) {
+
+	postService := &stubPostService{}
+	var service contracts.PostServiceContract = postService
+	urlResolver := services.NewURLResolver("", "", nil, 0)
+	server := New(&service, &urlResolver, time.Second)
+
+	t.Run("Fetch Reports Missing And Invalid IDs", func(t *testing.T) {
+		wallet := models.Post{ID: primitive.NewObjectID(), Title: "Wallet"}
+		keys := models.Post{ID: primitive.NewObjectID(), Title: "Keys"}
+		missing := primitive.NewObjectID().Hex()
+		postService.existing = []models.Post{wallet, keys}
+
+		posts, err := server.Fetch(context.Background(), &ps.PostIDs{Id: []string{keys.ID.Hex(), "42", missing, wallet.ID.Hex(), "42"}})
+		assert.NoError(t, err)
+		assert.Len(t, posts.List, 2)
+		assert.Equal(t, "Keys", posts.List[0].Title)
+		assert.Equal(t, "Wallet", posts.List[1].Title)
+		assert.Equal(t, []string{missing}, posts.NotFound)
+		assert.Equal(t, []string{"42"}, posts.Invalid)
+
+		_, err = server.Fetch(context.Background(), &ps.PostIDs{Id: make([]string, contracts.MaxPostIDs+1)})
+		assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+	})
+
+	t.Run("WatchPosts Streams Changes", func(t *testing.T) {
+		post := models.Post{ID: primitive.NewObjectID(), Title: "Wallet", UserID: 7}
+		postService.changes = []contracts.PostChange{
+			{Type: contracts.PostChangeUpdated, PostID: post.ID.Hex(), Post: &post, ResumeToken: "token-1", ChangedAt: time.Unix(1700000000, 0)},
+			{Type: contracts.PostChangeDeleted, PostID: post.ID.Hex(), ResumeToken: "token-2", ChangedAt: time.Unix(1700000001, 0)},
+		}
+
+		stream := &watchStream{}
+		err := server.WatchPosts(&ps.WatchPostsRequest{PostIds: []string{post.ID.Hex()}, ResumeToken: "token-0"}, stream)
+
+		assert.Equal(t, codes.OutOfRange, grpcstatus.Code(err))
+		assert.Equal(t, []string{post.ID.Hex()}, postService.watchFilter.PostIDs)
+		assert.Len(t, stream.sent, 2)
+		assert.Equal(t, "Wallet", stream.sent[0].Post.Title)
+		assert.Equal(t, "token-1", stream.sent[0].ResumeToken)
+		assert.Equal(t, "deleted", stream.sent[1].Type)
+		assert.Nil(t, stream.sent[1].Post)
+
+		err = server.WatchPosts(&ps.WatchPostsRequest{PostIds: []string{"42"}}, &watchStream{})
+		assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+	})
+
+	t.Run("Maps Metadata Onto Authenticated Request", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			"x-user-id", "7", "x-user-role", "user", "x-user-permission", "c,u,d", "x-user-name", "budi",
+		))
+
+		var authenticated *middleware.AuthenticatedRequest
+		_, err := AuthInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/model.PostService/Delete"},
+			func(ctx context.Context, req any) (any, error) {
+				authenticated, _ = ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest)
+				return nil, nil
+			})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &middleware.AuthenticatedRequest{UserID: "7", Role: "user", Permissions: "c,u,d", Username: "budi"}, authenticated)
+	})
+
+	t.Run("Rejects Unauthenticated Calls Except Fetch", func(t *testing.T) {
+		handler := func(ctx context.Context, req any) (any, error) { return nil, nil }
+
+		for _, method := range []string{"Create", "Get", "List", "Search"} {
+			_, err := AuthInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/model.PostService/" + method}, handler)
+			assert.Equal(t, codes.Unauthenticated, grpcstatus.Code(err), method)
+		}
+
+		_, err := AuthInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/model.PostService/Fetch"}, handler)
+		assert.NoError(t, err)
+	})
+
+	t.Run("List Applies REST Filters", func(t *testing.T) {
+		posts, err := server.List(context.Background(), &ps.ListPostsRequest{UserId: 7, Type: "lost"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"is_returned": false, "user_id": int64(7), "type": "lost"}, postService.filter)
+		assert.Equal(t, int64(1), posts.Page)
+		assert.Equal(t, int64(10), posts.Limit)
+		assert.Equal(t, "Wallet", posts.List[0].Title)
+
+		_, err = server.List(context.Background(), &ps.ListPostsRequest{Status: "returned"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"status": "returned"}, postService.filter)
+
+		_, err = server.List(context.Background(), &ps.ListPostsRequest{Status: "unknown"})
+		assert.Equal(t, codes.InvalidArgument, grpcstatus.Code(err))
+	})
+
+	t.Run("Maps Operation Status To Codes", func(t *testing.T) {
+		cases := []struct {
+			opStatus status.PostOperationStatus
+			err      error
+			code     codes.Code
+		}{
+			{status.OperationForbidden, errors.New("not the owner"), codes.PermissionDenied},
+			{status.OperationUnauthorized, errors.New("no permission"), codes.Unauthenticated},
+			{status.PostDeletedStatusFailed, mongo.ErrNoDocuments, codes.NotFound},
+			{status.PostDeletedStatusFailed, primitive.ErrInvalidHex, codes.InvalidArgument},
+			{status.PostDeletedStatusFailed, errors.New("connection reset"), codes.Internal},
+		}
+
+		for _, c := range cases {
+			postService.deleteOp, postService.deleteErr = c.opStatus, c.err
+			_, err := server.Delete(context.Background(), &ps.DeletePostRequest{Id: "id"})
+			assert.Equal(t, c.code, grpcstatus.Code(err), c.err.Error())
+		}
+	})
+
+	t.Run("Converts Posts", func(t *testing.T) {
+		created := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+		post := toProtoPost(models.Post{
+			ID:              primitive.NewObjectID(),
+			Title:           "Wallet",
+			IsReturned:      true,
+			Characteristics: []models.Characteristic{{Title: "brown"}},
+			CreatedAt:       &created,
+		})
+
+		assert.Equal(t, "Wallet", post.Name)
+		assert.Equal(t, string(models.PostStatusReturned), post.Status)
+		assert.Equal(t, "brown", post.Characteristics[0].Title)
+		assert.Equal(t, created, post.CreatedAt.AsTime())
+		assert.Nil(t, post.UpdatedAt)
+	})
+}
@@ -0,0 +1,166 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Server is a listener that serves until it is shut down. Run returns nil after Shutdown
+type Server interface {
+	Run() error
+	Shutdown(ctx context.Context) error
+}
+
+type namedServer struct {
+	name   string
+	server Server
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// Lifecycle starts the servers together and, once the process is asked to stop or a server
+// fails, drains them, stops the background workers and finally closes the clients in order
+type Lifecycle struct {
+	drainTimeout time.Duration
+	closeTimeout time.Duration
+
+	servers []namedServer
+	closers []closer
+
+	workers       sync.WaitGroup
+	workerCtx     context.Context
+	cancelWorkers context.CancelFunc
+}
+
+// Go runs a background worker, its context is canceled when the shutdown starts
+func (l *Lifecycle) Go(name string, worker func(ctx context.Context)) {
+	l.workers.Add(1)
+	go func() {
+		defer l.workers.Done()
+		worker(l.workerCtx)
+		log.Printf("Worker %s stopped", name)
+	}()
+}
+
+func (l *Lifecycle) Serve(name string, server Server) {
+	l.servers = append(l.servers, namedServer{name: name, server: server})
+}
+
+// OnClose registers a client to close after the servers and workers stopped, in registration order
+func (l *Lifecycle) OnClose(name string, close func(ctx context.Context) error) {
+	l.closers = append(l.closers, closer{name: name, close: close})
+}
+
+// Run blocks until the context is done or a server fails, then shuts everything down.
+// It returns the error of the failed server
+func (l *Lifecycle) Run(ctx context.Context) error {
+
+	failed := make(chan error, len(l.servers))
+	for _, s := range l.servers {
+		go func(s namedServer) {
+			log.Printf("Starting %s server", s.name)
+			if err := s.server.Run(); err != nil {
+				failed <- errors.New(s.name + " server: " + err.Error())
+				return
+			}
+			failed <- nil
+		}(s)
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down")
+	case runErr = <-failed:
+		log.Printf("Shutting down, %v", runErr)
+	}
+
+	l.shutdown()
+
+	return runErr
+}
+
+func (l *Lifecycle) shutdown() {
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), l.drainTimeout)
+	defer cancel()
+
+	//In-flight requests are drained while the workers finish their current run
+	l.cancelWorkers()
+
+	var servers sync.WaitGroup
+	for _, s := range l.servers {
+		servers.Add(1)
+		go func(s namedServer) {
+			defer servers.Done()
+			if err := s.server.Shutdown(drainCtx); err != nil {
+				log.Printf("Shutting down %s server: %v", s.name, err)
+			}
+		}(s)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		servers.Wait()
+		l.workers.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-drainCtx.Done():
+		log.Printf("Drain deadline of %v exceeded, closing anyway", l.drainTimeout)
+	}
+
+	for _, c := range l.closers {
+		closeCtx, cancel := context.WithTimeout(context.Background(), l.closeTimeout)
+		if err := c.close(closeCtx); err != nil {
+			log.Printf("Closing %s: %v", c.name, err)
+		} else {
+			log.Printf("Closed %s", c.name)
+		}
+		cancel()
+	}
+}
+
+type httpServer struct {
+	*http.Server
+}
+
+// HTTPServer adapts a net/http server, ErrServerClosed is not reported as a failure
+func HTTPServer(server *http.Server) Server {
+	return &httpServer{Server: server}
+}
+
+func (h *httpServer) Run() error {
+	if err := h.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func New(drainTimeout time.Duration, closeTimeout time.Duration) *Lifecycle {
+
+	if drainTimeout <= 0 {
+		drainTimeout = 20 * time.Second
+	}
+	if closeTimeout <= 0 {
+		closeTimeout = 5 * time.Second
+	}
+
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+
+	return &Lifecycle{
+		drainTimeout:  drainTimeout,
+		closeTimeout:  closeTimeout,
+		workerCtx:     workerCtx,
+		cancelWorkers: cancelWorkers,
+	}
+}
@@ -0,0 +1,121 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder collects the shutdown steps in the order they happen
+type recorder struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (r *recorder) add(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, step)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.steps...)
+}
+
+type fakeServer struct {
+	name    string
+	runErr  error
+	stopped chan struct{}
+	drain   time.Duration
+	steps   *recorder
+}
+
+func newFakeServer(name string, steps *recorder) *fakeServer {
+	return &fakeServer{name: name, stopped: make(chan struct{}), steps: steps}
+}
+
+func (f *fakeServer) Run() error {
+	if f.runErr != nil {
+		return f.runErr
+	}
+	<-f.stopped
+	return nil
+}
+
+func (f *fakeServer) Shutdown(ctx context.Context) error {
+	defer close(f.stopped)
+	select {
+	case <-time.After(f.drain):
+		f.steps.add("drained " + f.name)
+		return nil
+	case <-ctx.Done():
+		f.steps.add("aborted " + f.name)
+		return ctx.Err()
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+
+	t.Run("Drains Servers And Workers Before Closing In Order", func(t *testing.T) {
+		steps := &recorder{}
+		app := New(time.Second, time.Second)
+		app.Serve("http", newFakeServer("http", steps))
+		app.Go("relay", func(ctx context.Context) {
+			<-ctx.Done()
+			steps.add("stopped relay")
+		})
+		for _, name := range []string{"mongodb", "message broker", "storage"} {
+			name := name
+			app.OnClose(name, func(ctx context.Context) error {
+				steps.add("closed " + name)
+				return nil
+			})
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.NoError(t, app.Run(ctx))
+		assert.ElementsMatch(t, []string{"drained http", "stopped relay"}, steps.list()[:2])
+		assert.Equal(t, []string{"closed mongodb", "closed message broker", "closed storage"}, steps.list()[2:])
+	})
+
+	t.Run("Failing Server Stops The Others", func(t *testing.T) {
+		steps := &recorder{}
+		app := New(time.Second, time.Second)
+
+		failing := newFakeServer("grpc", steps)
+		failing.runErr = errors.New("address already in use")
+		app.Serve("grpc", failing)
+		app.Serve("http", newFakeServer("http", steps))
+
+		err := app.Run(context.Background())
+		assert.EqualError(t, err, "grpc server: address already in use")
+		assert.Contains(t, steps.list(), "drained http")
+	})
+
+	t.Run("Drain Deadline Aborts Slow Servers", func(t *testing.T) {
+		steps := &recorder{}
+		app := New(20*time.Millisecond, time.Second)
+
+		slow := newFakeServer("http", steps)
+		slow.drain = time.Minute
+		app.Serve("http", slow)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		start := time.Now()
+		assert.NoError(t, app.Run(ctx))
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual([]string{"aborted http"}, steps.list())
+		}, time.Second, 5*time.Millisecond)
+	})
+}
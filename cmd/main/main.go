@@ -1,13 +1,25 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
+	"golek_posts_service/cmd/bootstrap"
+	"golek_posts_service/cmd/grpc_server"
+	"golek_posts_service/cmd/jobs"
+	"golek_posts_service/cmd/lifecycle"
 	"golek_posts_service/cmd/msg_broker"
-	"golek_posts_service/pkg/database"
+	"golek_posts_service/pkg/contracts"
 	"golek_posts_service/pkg/database/migration"
 	"golek_posts_service/pkg/http/controllers"
 	"golek_posts_service/pkg/repositories"
 	"golek_posts_service/pkg/services"
+	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -24,76 +36,159 @@ func main() {
 	//Create Gin Instance
 	engine := gin.Default()
 
-	db := database.Database{
-		DbName:       os.Getenv("DB_NAME"),
-		DbCollection: os.Getenv("DB_COLLECTION"),
-		DbHost:       os.Getenv("DB_HOST"),
-		DbPort:       os.Getenv("DB_PORT_IN"),
-		DbUsername:   os.Getenv("DB_USERNAME"),
-		DBPassword:   os.Getenv("DB_PASSWORD"),
-	}
-	db.Prepare()
+	//Stop on SIGINT and SIGTERM, deploys get the drain timeout to finish in-flight requests
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	drainTimeout, _ := time.ParseDuration(os.Getenv("SHUTDOWN_DRAIN_TIMEOUT"))
+	closeTimeout, _ := time.ParseDuration(os.Getenv("SHUTDOWN_CLOSE_TIMEOUT"))
+	app := lifecycle.New(drainTimeout, closeTimeout)
+
+	db := bootstrap.NewDatabase()
+	app.OnClose("mongodb", db.Disconnect)
 
 	//Migrate DB
-	m := migration.NewMigration(&db)
+	m := migration.NewMigration(db)
 	m.MigrateSettings()
 
 	//Initialize Repositories
-	postRepository := repositories.NewPostRepository(db.GetConnection(), db.GetCollection())
-	qrcodeRepository := repositories.NewQRCodeRepository()
-	//awsS3Repository := repositories.NewS3Repository(
-	//	os.Getenv("AWS_ACCESS_KEY_ID"),
-	//	os.Getenv("AWS_SECRET_ACCESS_KEY"),
-	//	os.Getenv("AWS_BUCKET_NAME"),
-	//	os.Getenv("AWS_BUCKET_REGION"),
-	//	[]string{"image/jpeg", "image/png"},
-	//	int64(5*1024*1024), //MAX Filesize 5mb
-	//	3,
-	//)
+	postRepository := repositories.NewPostRepository(db.GetConnection(), db.GetCollection(), db.GetOutboxCollection(), db.GetAuditCollection())
+	claimRepository := repositories.NewClaimRepository(db.GetClaimCollection())
+	auditRepository := repositories.NewAuditRepository(db.GetAuditCollection())
+	outboxRepository := repositories.NewOutboxRepository(db.GetOutboxCollection())
+	qrCodeSize, _ := strconv.Atoi(os.Getenv("QR_CODE_SIZE"))
+	qrcodeRepository := repositories.NewQRCodeRepository(qrCodeSize, os.Getenv("QR_CODE_RECOVERY_LEVEL"))
 
 	//Setup Cloud Storage Service
-	//wd, _ := os.Getwd()
-	googleCloudStorage := repositories.NewGCStorageService("ayocode1-bucker", "keys.json")
-	err = googleCloudStorage.Connect()
+	cloudStorage, err := bootstrap.NewStorage()
 	if err != nil {
 		panic(err)
 	}
 
-	//Establish Message Broker Connection
-	amqpConn := msg_broker.New(
-		os.Getenv("RABBITMQ_USER"),
-		os.Getenv("RABBITMQ_PASSWORD"),
-		os.Getenv("RABBITMQ_HOST"),
-		os.Getenv("RABBITMQ_PORT"),
-	)
-	mqPublisherService := msg_broker.NewMQPublisher(amqpConn)
-	mqPublisherService.Setup()
+	//Posts uploaded before keys were stored only know their url
+	if cloudStorage, ok := cloudStorage.(*repositories.GoogleCloudStorageService); ok {
+		m.BackfillPostImageKeys(cloudStorage.KeyFromURL)
+	}
 
-	//Initialize Services
-	postService := services.NewPostService(&postRepository, &qrcodeRepository, &googleCloudStorage, &mqPublisherService)
+	//Serve locally stored files with the same url shape as the bucket
+	if localStorage, ok := cloudStorage.(*repositories.LocalStorageService); ok {
+		engine.Static(localStorage.Route(), localStorage.Dir())
+		engine.PUT(localStorage.UploadRoute()+"/*key", gin.WrapH(localStorage.UploadHandler()))
+	}
 
-	//Initialize Routes
-	controllers.SetupHandler(engine, &postService)
+	//Establish Message Broker Connection, the service keeps running while the broker is down
+	mqPublisherService := bootstrap.NewMessageQueue()
+	app.OnClose("message broker", func(ctx context.Context) error {
+		return mqPublisherService.Close()
+	})
+	app.OnClose("storage", func(ctx context.Context) error {
+		return cloudStorage.Close()
+	})
 
-	//Run grpc service in different thread
-	//go func(postService *contracts.PostServiceContract) {
-	//	grpcServer := grpc_server.New(postService)
-	//	grpcServer.Run()
-	//}(&postService)
+	//Initialize Services
+	validationTokenTTL, _ := time.ParseDuration(os.Getenv("VALIDATION_TOKEN_TTL"))
+	validationTokenService, err := services.NewValidationTokenService(os.Getenv("VALIDATION_SECRET"), validationTokenTTL)
+	if err != nil {
+		panic(err)
+	}
 
-	//Running App With Desired Port
-	if port := os.Getenv("APP_PORT"); port == "" {
+	matchingMinScore, _ := strconv.ParseFloat(bootstrap.GetEnv("MATCHING_MIN_SCORE", "0.2"), 64)
+	matchingService := services.NewMatchingService(&postRepository, 200, matchingMinScore)
+
+	imageMaxDimension, _ := strconv.Atoi(os.Getenv("IMAGE_MAX_DIMENSION"))
+	imageMediumDimension, _ := strconv.Atoi(os.Getenv("IMAGE_MEDIUM_DIMENSION"))
+	imageSmallDimension, _ := strconv.Atoi(os.Getenv("IMAGE_SMALL_DIMENSION"))
+	imageQuality, _ := strconv.Atoi(os.Getenv("IMAGE_QUALITY"))
+	imageMaxPixels, _ := strconv.Atoi(os.Getenv("IMAGE_MAX_PIXELS"))
+	imageProcessor := services.NewImageProcessor(imageMaxDimension, imageMediumDimension, imageSmallDimension, imageQuality, imageMaxPixels)
+
+	postMaxImages, _ := strconv.Atoi(os.Getenv("POST_MAX_IMAGES"))
+	postService := services.NewPostService(&postRepository, &qrcodeRepository, &cloudStorage, &outboxRepository, &validationTokenService, &matchingService, &claimRepository, &auditRepository, &imageProcessor, postMaxImages)
+	claimService := services.NewClaimService(&claimRepository, &postRepository)
+
+	healthChecks := map[string]contracts.HealthChecker{"message_broker": mqPublisherService}
+
+	//Keep the user copied into posts in line with the auth service, its events only come through RabbitMQ
+	if bootstrap.GetEnv("MESSAGE_BROKER", "rabbitmq") == "rabbitmq" {
+		userSyncService := services.NewUserSyncService(&postRepository)
+		userEventsConsumer := msg_broker.NewUserEventsConsumer(bootstrap.RabbitMQURL(),
+			bootstrap.GetEnv("USER_EVENTS_EXCHANGE", "users_exchange"),
+			bootstrap.GetEnv("USER_EVENTS_QUEUE", "posts_service.user_events"),
+			userSyncService,
+		)
+		app.Go("user-events", userEventsConsumer.Run)
+		healthChecks["user_events"] = userEventsConsumer
+	}
 
-		err := engine.Run(":8080")
-		if err != nil {
-			panic(err)
-		}
+	//Publish the events stored in the outbox
+	outboxRelayInterval, err := time.ParseDuration(bootstrap.GetEnv("OUTBOX_RELAY_INTERVAL", "5s"))
+	if err != nil {
+		panic(err)
+	}
+	outboxMaxAttempts, _ := strconv.Atoi(os.Getenv("OUTBOX_MAX_ATTEMPTS"))
+	outboxRelay := services.NewOutboxRelay(&outboxRepository, &mqPublisherService, 100, outboxMaxAttempts)
+	app.Go("outbox-relay", func(ctx context.Context) {
+		jobs.Every(ctx, "outbox-relay", outboxRelayInterval, func(ctx context.Context) error {
+			_, err := outboxRelay.Relay(ctx)
+			return err
+		})
+	})
+
+	//Expire posts which stayed open for too long
+	if expiryDays, _ := strconv.Atoi(os.Getenv("POST_EXPIRY_DAYS")); expiryDays > 0 {
+		app.Go("expire-posts", func(ctx context.Context) {
+			jobs.Every(ctx, "expire-posts", time.Hour, func(ctx context.Context) error {
+				expired, err := postService.ExpireStale(ctx, time.Duration(expiryDays)*24*time.Hour)
+				if err != nil {
+					return err
+				}
+				if expired > 0 {
+					log.Printf("Expired %d stale posts", expired)
+				}
+				return nil
+			})
+		})
+	}
 
-	} else {
+	//Permanently remove posts once their retention period passed
+	purgeRetentionDays, err := strconv.Atoi(bootstrap.GetEnv("PURGE_RETENTION_DAYS", "30"))
+	if err != nil {
+		panic(err)
+	}
+	app.Go("purge-posts", func(ctx context.Context) {
+		jobs.Every(ctx, "purge-posts", time.Hour, func(ctx context.Context) error {
+			purged, err := postService.PurgeDeleted(ctx, time.Duration(purgeRetentionDays)*24*time.Hour)
+			if err != nil {
+				return err
+			}
+			if purged > 0 {
+				log.Printf("Purged %d deleted posts", purged)
+			}
+			return nil
+		})
+	})
+
+	//Serve images from the CDN when configured
+	cdnURLTTL, _ := time.ParseDuration(os.Getenv("CDN_URL_TTL"))
+	cdnSigningKey, err := base64.URLEncoding.DecodeString(os.Getenv("CDN_SIGNING_KEY"))
+	if err != nil {
+		panic("CDN_SIGNING_KEY must be the base64url encoded Cloud CDN key: " + err.Error())
+	}
+	urlResolver := services.NewURLResolver(os.Getenv("CDN_BASE_URL"), os.Getenv("CDN_SIGNING_KEY_NAME"), cdnSigningKey, cdnURLTTL)
 
-		err := engine.Run(":" + port)
-		if err != nil {
-			panic(err)
-		}
+	//Initialize Routes
+	controllers.SetupHandler(engine, &postService, &claimService, &urlResolver)
+	controllers.SetupHealthHandler(engine, healthChecks)
+
+	//Serve HTTP and gRPC side by side until the process is stopped
+	app.Serve("http", lifecycle.HTTPServer(&http.Server{
+		Addr:    ":" + bootstrap.GetEnv("APP_PORT", "8080"),
+		Handler: engine,
+	}))
+	rpcTimeout, _ := time.ParseDuration(os.Getenv("RPC_DEFAULT_TIMEOUT"))
+	app.Serve("grpc", grpc_server.New(&postService, &urlResolver, rpcTimeout))
+
+	if err := app.Run(ctx); err != nil {
+		log.Fatal(err)
 	}
 }
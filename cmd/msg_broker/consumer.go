@@ -0,0 +1,195 @@
+package msg_broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golek_posts_service/pkg/contracts"
+	"log"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// UserEventsConsumer applies the user events of the auth service to the posts. Messages which
+// can't be applied, either malformed or still failing after the retries, are dead-lettered
+type UserEventsConsumer struct {
+	url         string
+	exchange    string
+	queue       string
+	handler     contracts.UserSyncContract
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	healthy     int32
+}
+
+func NewUserEventsConsumer(url string, exchange string, queue string, handler contracts.UserSyncContract) *UserEventsConsumer {
+	return &UserEventsConsumer{
+		url:         url,
+		exchange:    exchange,
+		queue:       queue,
+		handler:     handler,
+		maxAttempts: 3,
+		minBackoff:  time.Second,
+		maxBackoff:  30 * time.Second,
+	}
+}
+
+func (u *UserEventsConsumer) Healthy() bool {
+	return atomic.LoadInt32(&u.healthy) == 1
+}
+
+// Run consumes until the context is done, reconnecting whenever the connection drops
+func (u *UserEventsConsumer) Run(ctx context.Context) {
+
+	for attempt := 0; ; attempt++ {
+
+		err := u.consume(ctx)
+		atomic.StoreInt32(&u.healthy, 0)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			attempt = 0
+		}
+		log.Printf("User events consumer stopped, reconnecting: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff(attempt, u.minBackoff, u.maxBackoff)):
+		}
+	}
+}
+
+func (u *UserEventsConsumer) consume(ctx context.Context) error {
+
+	conn, err := Dial(u.url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+
+	if err := u.declare(channel); err != nil {
+		return err
+	}
+
+	if err := channel.Qos(10, 0, false); err != nil {
+		return err
+	}
+
+	deliveries, err := channel.Consume(u.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	atomic.StoreInt32(&u.healthy, 1)
+	log.Printf("Consuming user events from %s", u.queue)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return errors.New("delivery channel closed")
+			}
+			u.handle(ctx, delivery)
+		}
+	}
+}
+
+// declareChannel is the part of *amqp.Channel used to declare the topology
+type declareChannel interface {
+	ExchangeDeclare(name string, kind string, durable bool, autoDelete bool, internal bool, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable bool, autoDelete bool, exclusive bool, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name string, key string, exchange string, noWait bool, args amqp.Table) error
+}
+
+// declare sets up the queue bound to the user events and its dead letter queue
+func (u *UserEventsConsumer) declare(channel declareChannel) error {
+
+	deadLetterExchange := u.queue + ".dlx"
+
+	err := channel.ExchangeDeclare(u.exchange, "topic", true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	err = channel.ExchangeDeclare(deadLetterExchange, "fanout", true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := channel.QueueDeclare(u.queue+".dlq", true, false, false, false, nil); err != nil {
+		return err
+	}
+
+	if err := channel.QueueBind(u.queue+".dlq", "", deadLetterExchange, false, nil); err != nil {
+		return err
+	}
+
+	_, err = channel.QueueDeclare(u.queue, true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange": deadLetterExchange,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, eventType := range []contracts.UserEventType{contracts.UserEventUpdated, contracts.UserEventDeleted} {
+		if err := channel.QueueBind(u.queue, string(eventType)+".*", u.exchange, false, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *UserEventsConsumer) handle(ctx context.Context, delivery amqp.Delivery) {
+
+	var event contracts.UserEvent
+	if err := json.Unmarshal(delivery.Body, &event); err != nil {
+		u.deadLetter(delivery, fmt.Errorf("%w: %v", contracts.ErrInvalidUserEvent, err))
+		return
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = u.handler.HandleUserEvent(ctx, event); err == nil {
+			if err := delivery.Ack(false); err != nil {
+				log.Printf("User events: ack %s: %v", event.ID, err)
+			}
+			return
+		}
+		if errors.Is(err, contracts.ErrInvalidUserEvent) || attempt >= u.maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			//Shutting down, another instance picks the event up
+			if err := delivery.Nack(false, true); err != nil {
+				log.Printf("User events: requeue %s: %v", event.ID, err)
+			}
+			return
+		case <-time.After(backoff(attempt-1, u.minBackoff, u.maxBackoff)):
+		}
+	}
+
+	u.deadLetter(delivery, err)
+}
+
+func (u *UserEventsConsumer) deadLetter(delivery amqp.Delivery, reason error) {
+	log.Printf("User events: dead-lettering %s (%s): %v", delivery.MessageId, delivery.RoutingKey, reason)
+	if err := delivery.Nack(false, false); err != nil {
+		log.Printf("User events: nack %s: %v", delivery.MessageId, err)
+	}
+}
@@ -0,0 +1,162 @@
+package msg_broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golek_posts_service/pkg/contracts"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordedAcks stands in for the channel acknowledging the delivery
+type recordedAcks struct {
+	acked   bool
+	nacked  bool
+	requeue bool
+}
+
+func (r *recordedAcks) Ack(tag uint64, multiple bool) error {
+	r.acked = true
+	return nil
+}
+
+func (r *recordedAcks) Nack(tag uint64, multiple bool, requeue bool) error {
+	r.nacked, r.requeue = true, requeue
+	return nil
+}
+
+func (r *recordedAcks) Reject(tag uint64, requeue bool) error {
+	return r.Nack(tag, false, requeue)
+}
+
+// failingSync fails the first failures events it handles with err
+type failingSync struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *failingSync) HandleUserEvent(ctx context.Context, event contracts.UserEvent) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+// declaredTopology records the exchanges, queues and bindings declared on the channel
+type declaredTopology struct {
+	exchanges map[string]string
+	queues    map[string]amqp.Table
+	bindings  []string
+}
+
+func (d *declaredTopology) ExchangeDeclare(name string, kind string, durable bool, autoDelete bool, internal bool, noWait bool, args amqp.Table) error {
+	d.exchanges[name] = kind
+	return nil
+}
+
+func (d *declaredTopology) QueueDeclare(name string, durable bool, autoDelete bool, exclusive bool, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	d.queues[name] = args
+	return amqp.Queue{Name: name}, nil
+}
+
+func (d *declaredTopology) QueueBind(name string, key string, exchange string, noWait bool, args amqp.Table) error {
+	d.bindings = append(d.bindings, fmt.Sprintf("%s <- %s %s", name, exchange, key))
+	return nil
+}
+
+func TestUserEventsConsumer(t *testing.T) {
+
+	newConsumer := func(handler *failingSync) *UserEventsConsumer {
+		consumer := NewUserEventsConsumer("amqp://localhost", "users", "posts.users", handler)
+		consumer.minBackoff = time.Millisecond
+		consumer.maxBackoff = time.Millisecond
+		return consumer
+	}
+
+	deliver := func(body string) (amqp.Delivery, *recordedAcks) {
+		acks := &recordedAcks{}
+		return amqp.Delivery{Acknowledger: acks, Body: []byte(body), RoutingKey: "user.updated.v1"}, acks
+	}
+
+	event := `{"id":"1","type":"user.updated","user":{"id":7,"username":"finder"}}`
+
+	t.Run("Acks Handled Events", func(t *testing.T) {
+		handler := &failingSync{}
+		delivery, acks := deliver(event)
+
+		newConsumer(handler).handle(context.Background(), delivery)
+		assert.True(t, acks.acked)
+		assert.Equal(t, 1, handler.calls)
+	})
+
+	t.Run("Dead Letters Malformed Bodies", func(t *testing.T) {
+		handler := &failingSync{}
+		delivery, acks := deliver("{not json")
+
+		newConsumer(handler).handle(context.Background(), delivery)
+		assert.True(t, acks.nacked)
+		assert.False(t, acks.requeue)
+		assert.Equal(t, 0, handler.calls)
+	})
+
+	t.Run("Retries Failing Events", func(t *testing.T) {
+		handler := &failingSync{failures: 2, err: errors.New("database unavailable")}
+		delivery, acks := deliver(event)
+
+		newConsumer(handler).handle(context.Background(), delivery)
+		assert.True(t, acks.acked)
+		assert.Equal(t, 3, handler.calls)
+	})
+
+	t.Run("Dead Letters Events Failing Every Attempt", func(t *testing.T) {
+		handler := &failingSync{failures: 10, err: errors.New("database unavailable")}
+		delivery, acks := deliver(event)
+
+		newConsumer(handler).handle(context.Background(), delivery)
+		assert.True(t, acks.nacked)
+		assert.False(t, acks.requeue)
+		assert.Equal(t, 3, handler.calls)
+	})
+
+	t.Run("Dead Letters Invalid Events Without Retrying", func(t *testing.T) {
+		handler := &failingSync{failures: 10, err: contracts.ErrInvalidUserEvent}
+		delivery, acks := deliver(event)
+
+		newConsumer(handler).handle(context.Background(), delivery)
+		assert.True(t, acks.nacked)
+		assert.Equal(t, 1, handler.calls)
+	})
+
+	t.Run("Requeues On Shutdown", func(t *testing.T) {
+		handler := &failingSync{failures: 10, err: errors.New("database unavailable")}
+		delivery, acks := deliver(event)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		consumer := newConsumer(handler)
+		consumer.minBackoff, consumer.maxBackoff = time.Hour, time.Hour
+		consumer.handle(ctx, delivery)
+		assert.True(t, acks.nacked)
+		assert.True(t, acks.requeue)
+	})
+
+	t.Run("Declares The Dead Letter Queue", func(t *testing.T) {
+		topology := &declaredTopology{exchanges: map[string]string{}, queues: map[string]amqp.Table{}}
+
+		assert.NoError(t, newConsumer(&failingSync{}).declare(topology))
+		assert.Equal(t, map[string]string{"users": "topic", "posts.users.dlx": "fanout"}, topology.exchanges)
+		assert.Equal(t, "posts.users.dlx", topology.queues["posts.users"]["x-dead-letter-exchange"])
+		assert.Contains(t, topology.queues, "posts.users.dlq")
+		assert.ElementsMatch(t, []string{
+			"posts.users.dlq <- posts.users.dlx ",
+			"posts.users <- users " + string(contracts.UserEventUpdated) + ".*",
+			"posts.users <- users " + string(contracts.UserEventDeleted) + ".*",
+		}, topology.bindings)
+	})
+}
@@ -0,0 +1,28 @@
+package msg_broker
+
+import (
+	"golek_posts_service/pkg/contracts"
+	"log"
+)
+
+// LogPublisher only logs the messages, for local development without a broker
+type LogPublisher struct{}
+
+func NewLogPublisher() contracts.MessageQueue {
+	return &LogPublisher{}
+}
+
+func (l *LogPublisher) Publish(routingKey string, payload []byte) error {
+	log.Printf("[x] Publish %s %s", routingKey, string(payload))
+	return nil
+}
+
+func (l *LogPublisher) Setup() {}
+
+func (l *LogPublisher) Close() error {
+	return nil
+}
+
+func (l *LogPublisher) Healthy() bool {
+	return true
+}
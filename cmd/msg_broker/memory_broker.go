@@ -0,0 +1,90 @@
+package msg_broker
+
+import (
+	"encoding/json"
+	"golek_posts_service/pkg/contracts"
+	"sync"
+)
+
+type PublishedMessage struct {
+	RoutingKey string
+	Payload    []byte
+}
+
+// MemoryBroker records the published messages so tests can assert on them.
+// FailWith simulates an outage, every publish returns the error until it is reset with nil
+type MemoryBroker struct {
+	mu       sync.Mutex
+	messages []PublishedMessage
+	err      error
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (m *MemoryBroker) Publish(routingKey string, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+
+	m.messages = append(m.messages, PublishedMessage{RoutingKey: routingKey, Payload: append([]byte(nil), payload...)})
+	return nil
+}
+
+func (m *MemoryBroker) Setup() {}
+
+func (m *MemoryBroker) Close() error {
+	return nil
+}
+
+func (m *MemoryBroker) Healthy() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err != contracts.ErrBrokerUnavailable
+}
+
+func (m *MemoryBroker) FailWith(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+// Messages returns the messages published with the routing key, all of them when it is empty
+func (m *MemoryBroker) Messages(routingKey string) []PublishedMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := make([]PublishedMessage, 0, len(m.messages))
+	for _, message := range m.messages {
+		if routingKey == "" || message.RoutingKey == routingKey {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// PostEvents decodes the published domain events of the given type
+func (m *MemoryBroker) PostEvents(eventType contracts.PostEventType) ([]contracts.PostEvent, error) {
+
+	messages := m.Messages(contracts.PostEventRoutingKey(eventType, contracts.PostEventVersion))
+
+	events := make([]contracts.PostEvent, 0, len(messages))
+	for _, message := range messages {
+		var event contracts.PostEvent
+		if err := json.Unmarshal(message.Payload, &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (m *MemoryBroker) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages, m.err = nil, nil
+}
@@ -0,0 +1,137 @@
+package msg_broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golek_posts_service/pkg/contracts"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher publishes to a JetStream stream, the routing key becomes the subject suffix.
+// The client reconnects by itself and every publish waits for the stream to acknowledge it
+type NATSPublisher struct {
+	url            string
+	stream         string
+	publishTimeout time.Duration
+
+	mu       sync.Mutex
+	conn     *nats.Conn
+	js       nats.JetStreamContext
+	declared bool
+}
+
+func NewNATSPublisher(url string, stream string) contracts.MessageQueue {
+	return &NATSPublisher{url: url, stream: stream, publishTimeout: 5 * time.Second}
+}
+
+// Subject maps a routing key onto the stream, e.g. post.created.v1 becomes posts.post.created.v1
+func (n *NATSPublisher) Subject(routingKey string) string {
+	return "posts." + routingKey
+}
+
+func (n *NATSPublisher) Publish(routingKey string, payload []byte) error {
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.js == nil || !n.conn.IsConnected() {
+		return contracts.ErrBrokerUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.publishTimeout)
+	defer cancel()
+
+	if err := n.declare(); err != nil {
+		return err
+	}
+
+	messageID := newMessageID()
+	_, err := n.js.Publish(n.Subject(routingKey), payload, nats.MsgId(messageID), nats.Context(ctx))
+	if errors.Is(err, nats.ErrNoStreamResponse) {
+		//The stream is gone or not ready yet, declare it again on the next attempt
+		n.declared = false
+		return fmt.Errorf("stream %s did not respond: %w", n.stream, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[x] Sent %s %s", routingKey, messageID)
+
+	return nil
+}
+
+// Setup connects in the background, the client keeps retrying while the server is down
+func (n *NATSPublisher) Setup() {
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn != nil {
+		return
+	}
+
+	conn, err := nats.Connect(n.url,
+		nats.Name("golek_posts_service"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(2*time.Second),
+	)
+	if err != nil {
+		log.Printf("Failed to connect to NATS: %v", err)
+		return
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		log.Printf("Failed to open NATS JetStream: %v", err)
+		conn.Close()
+		return
+	}
+
+	n.conn, n.js = conn, js
+}
+
+func (n *NATSPublisher) Healthy() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.conn != nil && n.conn.IsConnected()
+}
+
+func (n *NATSPublisher) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn == nil {
+		return nil
+	}
+	return n.conn.Drain()
+}
+
+// declare creates the stream on first use, it can't be done while the server is unreachable
+func (n *NATSPublisher) declare() error {
+
+	if n.declared {
+		return nil
+	}
+
+	_, err := n.js.StreamInfo(n.stream)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = n.js.AddStream(&nats.StreamConfig{
+			Name:     n.stream,
+			Subjects: []string{n.Subject(">")},
+			Storage:  nats.FileStorage,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	n.declared = true
+	return nil
+}
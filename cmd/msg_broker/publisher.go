@@ -2,63 +2,268 @@ package msg_broker
 
 import (
 	"context"
-	amqp "github.com/rabbitmq/amqp091-go"
+	"crypto/rand"
+	"encoding/hex"
 	"golek_posts_service/pkg/contracts"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// MQPublisher publishes with publisher confirms and mandatory routing. It keeps reconnecting in the
+// background, so the service starts and keeps serving while the broker is down. Publishing is
+// serialized because an amqp channel must not be shared by concurrent publishers
 type MQPublisher struct {
-	amqpConnection *amqp.Connection
-	amqpChannel    *amqp.Channel
+	url            string
+	publishTimeout time.Duration
+	minBackoff     time.Duration
+	maxBackoff     time.Duration
+
+	mu       sync.Mutex
+	conn     *amqp.Connection
+	channel  publishChannel
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+
+	healthy   int32
+	once      sync.Once
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// publishChannel is the part of *amqp.Channel used to publish
+type publishChannel interface {
+	PublishWithContext(ctx context.Context, exchange string, key string, mandatory bool, immediate bool, msg amqp.Publishing) error
+	IsClosed() bool
+	Close() error
 }
 
-func (m *MQPublisher) Publish(payload []byte) error {
+func (m *MQPublisher) Publish(routingKey string, payload []byte) error {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.channel == nil || !m.Healthy() {
+		return contracts.ErrBrokerUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.publishTimeout)
 	defer cancel()
 
-	err := m.amqpChannel.PublishWithContext(ctx,
-		"posts_exchange", // exchange
-		"new_post_route", // routing key
-		false,            // mandatory
-		false,            // immediate
+	messageID := newMessageID()
+	err := m.channel.PublishWithContext(ctx,
+		contracts.PostsExchange, // exchange
+		routingKey,              // routing key
+		true,                    // mandatory
+		false,                   // immediate
 		amqp.Publishing{
-			ContentType: "text/plain",
-			Body:        payload,
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			MessageId:    messageID,
+			Timestamp:    time.Now(),
+			Body:         payload,
 		})
 	if err != nil {
 		return err
 	}
 
-	log.Printf("[x] Sent Notification %s", string(payload))
+	select {
+	case confirmation, ok := <-m.confirms:
+		if !ok {
+			return contracts.ErrBrokerUnavailable
+		}
+		if !confirmation.Ack {
+			return contracts.ErrMessageNacked
+		}
+	case <-ctx.Done():
+		//The late confirm would be mistaken for the next message, start over with a fresh channel
+		m.channel.Close()
+		return ctx.Err()
+	}
+
+	//The broker sends basic.return before the ack, so an unroutable message is already buffered here
+drain:
+	for {
+		select {
+		case returned := <-m.returns:
+			if returned.MessageId == messageID {
+				return contracts.ErrMessageUnroutable
+			}
+		default:
+			break drain
+		}
+	}
+
+	log.Printf("[x] Sent %s %s", routingKey, messageID)
 
 	return nil
+}
 
+func (m *MQPublisher) Healthy() bool {
+	return atomic.LoadInt32(&m.healthy) == 1
 }
 
-func NewMQPublisher(amqpConn *amqp.Connection) contracts.MessageQueue {
-	return &MQPublisher{amqpConnection: amqpConn}
+func NewMQPublisher(url string) contracts.MessageQueue {
+	return &MQPublisher{
+		url:            url,
+		publishTimeout: 5 * time.Second,
+		minBackoff:     time.Second,
+		maxBackoff:     30 * time.Second,
+		done:           make(chan struct{}),
+	}
 }
 
+// Setup connects once right away and keeps the connection alive in the background
 func (m *MQPublisher) Setup() {
+	m.once.Do(func() {
+		closed, err := m.connect()
+		if err != nil {
+			log.Printf("Failed to connect to RabbitMQ, retrying in the background: %v", err)
+		}
+		go m.keepAlive(closed)
+	})
+}
+
+// Close stops reconnecting and closes the channel, then the connection. It waits for an
+// in-flight publish to be confirmed first
+func (m *MQPublisher) Close() error {
+
+	m.closeOnce.Do(func() { close(m.done) })
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	atomic.StoreInt32(&m.healthy, 0)
+
+	var err error
+	if m.channel != nil && !m.channel.IsClosed() {
+		err = m.channel.Close()
+	}
+	if m.conn != nil && !m.conn.IsClosed() {
+		if connErr := m.conn.Close(); err == nil {
+			err = connErr
+		}
+	}
+	m.conn, m.channel = nil, nil
+
+	return err
+}
+
+func (m *MQPublisher) keepAlive(closed chan *amqp.Error) {
 
-	//open channel
-	channel, err := m.amqpConnection.Channel()
-	failOnError(err, "Failed to open a channel")
-	//defer channel.Close()
+	for attempt := 0; ; attempt++ {
+
+		if closed != nil {
+			select {
+			case <-m.done:
+				return
+			case err := <-closed:
+				atomic.StoreInt32(&m.healthy, 0)
+				log.Printf("RabbitMQ connection lost: %v", err)
+				attempt = 0
+				m.disconnect()
+			}
+		}
+
+		select {
+		case <-m.done:
+			return
+		case <-time.After(backoff(attempt, m.minBackoff, m.maxBackoff)):
+		}
+
+		var err error
+		closed, err = m.connect()
+		if err != nil {
+			log.Printf("Failed to reconnect to RabbitMQ: %v", err)
+		}
+	}
+}
+
+// disconnect drops the current connection, only the channel may have been closed
+func (m *MQPublisher) disconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn != nil && !m.conn.IsClosed() {
+		m.conn.Close()
+	}
+	m.conn, m.channel = nil, nil
+}
+
+// connect opens a confirming channel and returns a channel notified when either the
+// connection or the channel closes
+func (m *MQPublisher) connect() (chan *amqp.Error, error) {
+
+	conn, err := Dial(m.url)
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
 
 	//declare an exchange
 	err = channel.ExchangeDeclare(
-		"posts_exchange", // name
-		"topic",          // type
-		true,             // durable
-		false,            // auto-deleted
-		false,            // internal
-		false,            // no-wait
-		nil,              // arguments
+		contracts.PostsExchange, // name
+		"topic",                 // type
+		true,                    // durable
+		false,                   // auto-deleted
+		false,                   // internal
+		false,                   // no-wait
+		nil,                     // arguments
 	)
-	failOnError(err, "Failed to declare an exchange")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	closed := make(chan *amqp.Error, 2)
+	conn.NotifyClose(forward(closed))
+	channel.NotifyClose(forward(closed))
+
+	m.mu.Lock()
+	m.conn = conn
+	m.channel = channel
+	m.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	m.returns = channel.NotifyReturn(make(chan amqp.Return, 16))
+	m.mu.Unlock()
+
+	atomic.StoreInt32(&m.healthy, 1)
+	log.Printf("Connected to RabbitMQ")
+
+	return closed, nil
+}
+
+// forward merges the close notifications of the connection and the channel into one channel.
+// The library closes the notification channel after sending, so every source gets its own one
+func forward(merged chan *amqp.Error) chan *amqp.Error {
+	source := make(chan *amqp.Error, 1)
+	go func() {
+		err, ok := <-source
+		if !ok {
+			err = amqp.ErrClosed
+		}
+		select {
+		case merged <- err:
+		default:
+		}
+	}()
+	return source
+}
 
-	m.amqpChannel = channel
+func newMessageID() string {
+	id := make([]byte, 12)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
 }
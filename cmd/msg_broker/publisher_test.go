@@ -0,0 +1,94 @@
+package msg_broker
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeChannel answers every publish the way the broker configured by onPublish would
+type fakeChannel struct {
+	closed    bool
+	onPublish func(msg amqp.Publishing)
+}
+
+func (f *fakeChannel) PublishWithContext(ctx context.Context, exchange string, key string, mandatory bool, immediate bool, msg amqp.Publishing) error {
+	f.onPublish(msg)
+	return nil
+}
+
+func (f *fakeChannel) IsClosed() bool {
+	return f.closed
+}
+
+func (f *fakeChannel) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestMQPublisher(t *testing.T) {
+
+	newPublisher := func(onPublish func(publisher *MQPublisher, msg amqp.Publishing)) (*MQPublisher, *fakeChannel) {
+		publisher := NewMQPublisher("amqp://localhost").(*MQPublisher)
+		publisher.publishTimeout = 50 * time.Millisecond
+		publisher.confirms = make(chan amqp.Confirmation, 1)
+		publisher.returns = make(chan amqp.Return, 16)
+		publisher.healthy = 1
+
+		channel := &fakeChannel{onPublish: func(msg amqp.Publishing) { onPublish(publisher, msg) }}
+		publisher.channel = channel
+		return publisher, channel
+	}
+
+	t.Run("Confirms Published Messages", func(t *testing.T) {
+		publisher, _ := newPublisher(func(publisher *MQPublisher, msg amqp.Publishing) {
+			publisher.confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+		})
+
+		assert.NoError(t, publisher.Publish(contracts.NewPostRoutingKey, []byte("{}")))
+	})
+
+	t.Run("Reports Nacked Messages", func(t *testing.T) {
+		publisher, _ := newPublisher(func(publisher *MQPublisher, msg amqp.Publishing) {
+			publisher.confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: false}
+		})
+
+		assert.ErrorIs(t, publisher.Publish(contracts.NewPostRoutingKey, []byte("{}")), contracts.ErrMessageNacked)
+	})
+
+	t.Run("Reports Messages Returned Before The Ack", func(t *testing.T) {
+		publisher, _ := newPublisher(func(publisher *MQPublisher, msg amqp.Publishing) {
+			publisher.returns <- amqp.Return{MessageId: msg.MessageId, ReplyText: "NO_ROUTE"}
+			publisher.confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+		})
+
+		assert.ErrorIs(t, publisher.Publish("post.unknown.v1", []byte("{}")), contracts.ErrMessageUnroutable)
+	})
+
+	t.Run("Ignores Returns Of Earlier Messages", func(t *testing.T) {
+		publisher, _ := newPublisher(func(publisher *MQPublisher, msg amqp.Publishing) {
+			publisher.returns <- amqp.Return{MessageId: "earlier"}
+			publisher.confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+		})
+
+		assert.NoError(t, publisher.Publish(contracts.NewPostRoutingKey, []byte("{}")))
+	})
+
+	t.Run("Drops The Channel When The Confirm Times Out", func(t *testing.T) {
+		publisher, channel := newPublisher(func(publisher *MQPublisher, msg amqp.Publishing) {})
+
+		assert.ErrorIs(t, publisher.Publish(contracts.NewPostRoutingKey, []byte("{}")), context.DeadlineExceeded)
+		assert.True(t, channel.closed)
+	})
+
+	t.Run("Refuses To Publish While Disconnected", func(t *testing.T) {
+		publisher, _ := newPublisher(func(publisher *MQPublisher, msg amqp.Publishing) {})
+		publisher.healthy = 0
+
+		assert.ErrorIs(t, publisher.Publish(contracts.NewPostRoutingKey, []byte("{}")), contracts.ErrBrokerUnavailable)
+	})
+}
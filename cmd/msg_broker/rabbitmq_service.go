@@ -2,22 +2,27 @@ package msg_broker
 
 import (
 	"fmt"
-	"log"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
-func New(user string, password string, host string, port string) *amqp.Connection {
-	conn, err := amqp.Dial(fmt.Sprintf("amqp://%s:%s@%s:%s/", user, password, host, port))
-	if err != nil {
-		log.Fatalf("Failed to establish RabbitMQ connection: %v", err.Error())
-	}
-	log.Printf("Connected to RabbitMQ")
-	return conn
+func URL(user string, password string, host string, port string) string {
+	return fmt.Sprintf("amqp://%s:%s@%s:%s/", user, password, host, port)
+}
+
+func Dial(url string) (*amqp.Connection, error) {
+	return amqp.Dial(url)
 }
 
-func failOnError(err error, msg string) {
-	if err != nil {
-		log.Panicf("%s: %s", msg, err)
+// backoff doubles the delay after every failed attempt up to max
+func backoff(attempt int, min time.Duration, max time.Duration) time.Duration {
+	delay := min
+	for i := 0; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
 	}
+	return delay
 }
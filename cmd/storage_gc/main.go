@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"golek_posts_service/cmd/bootstrap"
+	"golek_posts_service/pkg/repositories"
+	"golek_posts_service/pkg/services"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Compares the stored objects against the image keys of the posts collection.
+// Orphans are only reported unless -delete is given, posts pointing at missing objects are always reported
+func main() {
+
+	deleteOrphans := flag.Bool("delete", false, "delete orphaned objects")
+	minAge := flag.Duration("min-age", 24*time.Hour, "ignore objects younger than this, they may belong to a post being created")
+	flag.Parse()
+
+	//Load .Env
+	err := godotenv.Load(".env")
+	if err != nil {
+		panic(err)
+	}
+
+	db := bootstrap.NewDatabase()
+	postRepository := repositories.NewPostRepository(db.GetConnection(), db.GetCollection(), db.GetOutboxCollection(), db.GetAuditCollection())
+
+	storage, err := bootstrap.NewStorage()
+	if err != nil {
+		panic(err)
+	}
+
+	gcService := services.NewStorageGCService(&postRepository, &storage, *minAge)
+	report, err := gcService.Reconcile(context.Background(), *deleteOrphans)
+	if err != nil {
+		log.Fatalf("Storage GC >> %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Scanned %d objects, %d orphans, %d deleted, %d missing", report.Scanned, len(report.Orphans), report.Deleted, len(report.Missing))
+}
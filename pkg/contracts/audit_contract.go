@@ -0,0 +1,26 @@
+package contracts
+
+import (
+	"context"
+	"golek_posts_service/pkg/models"
+)
+
+type auditContextKey struct{}
+
+// WithAudit attaches the entries to the context, the repository writing the change records them
+// in the same transaction so the history never misses a committed change
+func WithAudit(ctx context.Context, audits ...models.PostAudit) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, append(AuditsOf(ctx), audits...))
+}
+
+// AuditsOf returns the entries attached by WithAudit
+func AuditsOf(ctx context.Context) []models.PostAudit {
+	audits, _ := ctx.Value(auditContextKey{}).([]models.PostAudit)
+	return audits
+}
+
+// AuditRepositoryContract is append-only, recorded entries are never changed
+type AuditRepositoryContract interface {
+	Record(ctx context.Context, audit models.PostAudit) error
+	FetchByPost(ctx context.Context, postID string, limit int64, skip int64) ([]models.PostAudit, error)
+}
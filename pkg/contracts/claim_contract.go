@@ -0,0 +1,25 @@
+package contracts
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/requests"
+	"golek_posts_service/pkg/models"
+)
+
+type ClaimServiceContract interface {
+	Submit(ctx context.Context, postID string, request requests.CreateClaimRequest) (models.Claim, status.PostOperationStatus, error)
+	ListByPost(ctx context.Context, postID string) ([]models.Claim, status.PostOperationStatus, error)
+	Approve(ctx context.Context, postID string, claimID string) (models.Claim, status.PostOperationStatus, error)
+	Reject(ctx context.Context, postID string, claimID string) (models.Claim, status.PostOperationStatus, error)
+}
+
+type ClaimRepositoryContract interface {
+	Fetch(ctx context.Context, filter map[string]any) ([]models.Claim, error)
+	FindOne(ctx context.Context, filter map[string]any) (models.Claim, error)
+	FindById(ctx context.Context, claimID string) (models.Claim, error)
+	Create(ctx context.Context, claim models.Claim) (models.Claim, status.PostOperationStatus, error)
+	UpdateStatus(ctx context.Context, claimID string, from models.ClaimStatus, to models.ClaimStatus, decidedBy int64) (models.Claim, status.PostOperationStatus, error)
+	Delete(ctx context.Context, claimID string) error
+	DeleteByPost(ctx context.Context, postID string) (int64, error)
+}
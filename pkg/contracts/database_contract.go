@@ -1,13 +1,21 @@
 package contracts
 
-import "go.mongodb.org/mongo-driver/mongo"
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
 
 type DBContract interface {
 	Dsn() string
 	GetConnection() *mongo.Database
+	Disconnect(ctx context.Context) error
 }
 
 type MongoDBContract interface {
 	GetCollection() *mongo.Collection
+	GetClaimCollection() *mongo.Collection
+	GetAuditCollection() *mongo.Collection
+	GetOutboxCollection() *mongo.Collection
 	DBContract
 }
@@ -0,0 +1,30 @@
+package contracts
+
+import "errors"
+
+var (
+	ErrUnsupportedImage = errors.New("image format is not supported, use jpeg or png")
+	ErrImageTooLarge    = errors.New("image has too many pixels")
+)
+
+type ImageVariant string
+
+const (
+	ImageVariantOriginal ImageVariant = "original"
+	ImageVariantMedium   ImageVariant = "medium"
+	ImageVariantSmall    ImageVariant = "small"
+)
+
+type ProcessedImage struct {
+	Variant     ImageVariant
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+type ImageProcessorContract interface {
+	//Process strips metadata, applies the EXIF orientation and returns the
+	//re-encoded original followed by its medium and small thumbnails
+	Process(data []byte) ([]ProcessedImage, error)
+}
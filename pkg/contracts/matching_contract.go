@@ -0,0 +1,10 @@
+package contracts
+
+import (
+	"context"
+	"golek_posts_service/pkg/models"
+)
+
+type MatchingServiceContract interface {
+	FindMatches(ctx context.Context, post models.Post, limit int) ([]models.PostMatch, error)
+}
@@ -1,8 +1,29 @@
 package contracts
 
+import "errors"
+
+const (
+	NewPostRoutingKey   = "new_post_route"
+	PostMatchRoutingKey = "post_match_route"
+)
+
+var (
+	ErrBrokerUnavailable = errors.New("message broker is unavailable")
+	ErrMessageNacked     = errors.New("message was rejected by the broker")
+	ErrMessageUnroutable = errors.New("message could not be routed to any queue")
+)
+
 type MessageQueue interface {
-	Publish(payload []byte) error
+	Publish(routingKey string, payload []byte) error
 	Setup()
+	//Close releases the broker connection once nothing publishes anymore
+	Close() error
+	HealthChecker
+}
+
+// HealthChecker reports whether a dependency is currently usable
+type HealthChecker interface {
+	Healthy() bool
 }
 
 type MessagePayload struct {
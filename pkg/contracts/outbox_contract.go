@@ -0,0 +1,21 @@
+package contracts
+
+import (
+	"context"
+	"golek_posts_service/pkg/models"
+	"time"
+)
+
+type OutboxRepositoryContract interface {
+	Enqueue(ctx context.Context, events ...models.OutboxEvent) error
+	//ClaimDue leases up to limit pending events, other relays skip them until the lease ends
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error)
+	MarkDelivered(ctx context.Context, event models.OutboxEvent) error
+	//MarkFailed records the attempt, the event is retried at nextAttemptAt unless it gave up
+	MarkFailed(ctx context.Context, event models.OutboxEvent, nextAttemptAt time.Time, gaveUp bool) error
+}
+
+type OutboxRelayContract interface {
+	//Relay publishes the due events once and returns how many were delivered
+	Relay(ctx context.Context) (int, error)
+}
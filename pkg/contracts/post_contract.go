@@ -2,27 +2,62 @@ package contracts
 
 import (
 	"context"
+	"errors"
 	"golek_posts_service/pkg/contracts/status"
 	"golek_posts_service/pkg/http/requests"
 	"golek_posts_service/pkg/models"
+	"time"
 )
 
+var ErrPostStateConflict = errors.New("post status changed concurrently")
+var ErrTooManyPostIDs = errors.New("too many post ids")
+
+// MaxPostIDs caps the ids a single FindByIDs lookup may ask for
+const MaxPostIDs = 500
+
 type PostServiceContract interface {
 	Fetch(ctx context.Context, pagination models.Pagination, filter map[string]any) ([]models.Post, error)
 	FindById(ctx context.Context, postID string) (models.Post, error)
+	FindByIDs(ctx context.Context, postIDs []string) ([]models.Post, error)
 	Search(ctx context.Context, keyword string, pagination models.Pagination) ([]models.Post, error)
+	FindMatches(ctx context.Context, postID string, limit int) ([]models.PostMatch, error)
+	PresignUploads(ctx context.Context, request requests.PresignUploadRequest) ([]PresignedUpload, status.PostOperationStatus, error)
 	Create(ctx context.Context, request requests.CreatePostRequest) (models.Post, status.PostOperationStatus, error)
 	Update(ctx context.Context, postID string, request requests.UpdatePostRequest) (models.Post, status.PostOperationStatus, error)
 	Delete(ctx context.Context, postID string) (status.PostOperationStatus, error)
+	AddImages(ctx context.Context, postID string, request requests.AddPostImagesRequest) (models.Post, status.PostOperationStatus, error)
+	RemoveImage(ctx context.Context, postID string, imageID string) (models.Post, status.PostOperationStatus, error)
+	ReorderImages(ctx context.Context, postID string, request requests.ReorderPostImagesRequest) (models.Post, status.PostOperationStatus, error)
+	SetCoverImage(ctx context.Context, postID string, imageID string) (models.Post, status.PostOperationStatus, error)
+	Restore(ctx context.Context, postID string) (status.PostOperationStatus, error)
+	History(ctx context.Context, postID string, pagination models.Pagination) ([]models.PostAudit, status.PostOperationStatus, error)
+	PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error)
 	RequestValidateOwner(ctx context.Context, postID string) (qrCode string, status status.PostOperationStatus, err error)
+	RenderValidationQRCode(ctx context.Context, postID string, options QrCodeOptions) (image []byte, contentType string, status status.PostOperationStatus, err error)
 	ValidateOwner(ctx context.Context, request requests.ValidateItemOwnerRequest) (status.PostOperationStatus, error)
+	Archive(ctx context.Context, postID string) (status.PostOperationStatus, error)
+	ExpireStale(ctx context.Context, maxAge time.Duration) (int64, error)
+	Watch(ctx context.Context, filter PostWatchFilter, resumeToken string, fn func(change PostChange) error) error
 }
 
 type PostRepositoryContract interface {
 	Fetch(ctx context.Context, latest bool, limit int64, skip int64, filter map[string]any) ([]models.Post, error)
 	FindById(ctx context.Context, postID string) (models.Post, error)
+	FindByIDs(ctx context.Context, postIDs []string) ([]models.Post, error)
 	Search(ctx context.Context, keyword string, limit int64, skip int64) ([]models.Post, error)
-	Create(ctx context.Context, post models.Post) (models.Post, status.PostOperationStatus, error)
-	Update(ctx context.Context, postID string, post models.Post) (models.Post, status.PostOperationStatus, error)
-	Delete(ctx context.Context, postID string) (status.PostOperationStatus, error)
+	Create(ctx context.Context, post models.Post, events ...models.OutboxEvent) (models.Post, status.PostOperationStatus, error)
+	Update(ctx context.Context, postID string, post models.Post, events ...models.OutboxEvent) (models.Post, status.PostOperationStatus, error)
+	//UpdateUnchanged fails with ErrPostStateConflict when the post was updated after unchangedSince
+	UpdateUnchanged(ctx context.Context, postID string, unchangedSince *time.Time, post models.Post, events ...models.OutboxEvent) (models.Post, status.PostOperationStatus, error)
+	Delete(ctx context.Context, postID string, events ...models.OutboxEvent) (status.PostOperationStatus, error)
+	FindTrashedById(ctx context.Context, postID string) (models.Post, error)
+	FetchTrashed(ctx context.Context, deletedBefore time.Time, limit int64) ([]models.Post, error)
+	Restore(ctx context.Context, postID string, events ...models.OutboxEvent) (status.PostOperationStatus, error)
+	Purge(ctx context.Context, postID string) (status.PostOperationStatus, error)
+	Transition(ctx context.Context, postID string, from models.PostStatus, to models.PostStatus, match map[string]any, set map[string]any, events ...models.OutboxEvent) (status.PostOperationStatus, error)
+	//ExpireBefore stores the event built by eventOf for every expired post in the same transaction
+	ExpireBefore(ctx context.Context, createdBefore time.Time, eventOf func(expired models.Post) (models.OutboxEvent, error)) (int64, error)
+	EachImageReference(ctx context.Context, fn func(post models.Post) error) error
+	SyncUserInfo(ctx context.Context, userID int64, user models.UserInfo, syncedAt time.Time) (int64, error)
+	Watch(ctx context.Context, filter PostWatchFilter, resumeToken string, fn func(change PostChange) error) error
 }
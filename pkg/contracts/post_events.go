@@ -0,0 +1,85 @@
+package contracts
+
+import (
+	"fmt"
+	"golek_posts_service/pkg/models"
+	"time"
+)
+
+const PostsExchange = "posts_exchange"
+
+type PostEventType string
+
+const (
+	PostEventCreated             PostEventType = "post.created"
+	PostEventUpdated             PostEventType = "post.updated"
+	PostEventDeleted             PostEventType = "post.deleted"
+	PostEventValidationRequested PostEventType = "post.validation_requested"
+	PostEventReturned            PostEventType = "post.returned"
+	PostEventRestored            PostEventType = "post.restored"
+)
+
+// PostEventVersion is bumped whenever a field is removed or changes its meaning.
+// Adding fields keeps the version, consumers must ignore fields they don't know
+const PostEventVersion = 1
+
+// EventActor is the user who caused the event, the id is 0 and the role "system" for scheduled jobs
+type EventActor struct {
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// PostEventData is the state of the post right after the change
+type PostEventData struct {
+	ID              string                  `json:"id"`
+	UserID          int64                   `json:"user_id"`
+	Type            models.PostType         `json:"type"`
+	Status          models.PostStatus       `json:"status"`
+	Title           string                  `json:"title"`
+	Place           string                  `json:"place"`
+	Description     string                  `json:"description"`
+	ImageURL        string                  `json:"image_url"`
+	Characteristics []models.Characteristic `json:"characteristics"`
+	ReturnedTo      int64                   `json:"returned_to,omitempty"`
+	CreatedAt       *time.Time              `json:"created_at,omitempty"`
+	UpdatedAt       *time.Time              `json:"updated_at,omitempty"`
+	DeletedAt       *time.Time              `json:"deleted_at,omitempty"`
+}
+
+type PostEvent struct {
+	ID         string        `json:"id"`
+	Type       PostEventType `json:"type"`
+	Version    int           `json:"version"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	Actor      EventActor    `json:"actor"`
+	Post       PostEventData `json:"post"`
+	//ChangedFields lists the stored fields touched by an update
+	ChangedFields []string `json:"changed_fields,omitempty"`
+}
+
+// RoutingKey is the type suffixed with the schema version, e.g. post.created.v1
+func (e PostEvent) RoutingKey() string {
+	return PostEventRoutingKey(e.Type, e.Version)
+}
+
+func PostEventRoutingKey(eventType PostEventType, version int) string {
+	return fmt.Sprintf("%s.v%d", eventType, version)
+}
+
+func NewPostEventData(post models.Post) PostEventData {
+	return PostEventData{
+		ID:              post.ID.Hex(),
+		UserID:          post.UserID,
+		Type:            post.Type,
+		Status:          post.CurrentStatus(),
+		Title:           post.Title,
+		Place:           post.Place,
+		Description:     post.Description,
+		ImageURL:        post.ImageURL,
+		Characteristics: post.Characteristics,
+		ReturnedTo:      post.ReturnedTo,
+		CreatedAt:       post.CreatedAt,
+		UpdatedAt:       post.UpdatedAt,
+		DeletedAt:       post.DeletedAt,
+	}
+}
@@ -0,0 +1,36 @@
+package contracts
+
+import (
+	"errors"
+	"golek_posts_service/pkg/models"
+	"time"
+)
+
+var (
+	ErrInvalidResumeToken = errors.New("resume token is invalid")
+	ErrResumeTokenExpired = errors.New("resume token is no longer in the change history")
+)
+
+type PostChangeType string
+
+const (
+	PostChangeCreated PostChangeType = "created"
+	PostChangeUpdated PostChangeType = "updated"
+	PostChangeDeleted PostChangeType = "deleted"
+)
+
+// PostWatchFilter narrows a watch down to some posts, an empty filter watches every post
+type PostWatchFilter struct {
+	PostIDs []string
+	UserID  int64
+}
+
+// PostChange is one change of the posts collection. Post is nil once the post is purged,
+// ResumeToken restarts a watch right after this change
+type PostChange struct {
+	Type        PostChangeType
+	PostID      string
+	Post        *models.Post
+	ResumeToken string
+	ChangedAt   time.Time
+}
@@ -1,5 +1,25 @@
 package contracts
 
+import "errors"
+
+var ErrInvalidQrCodeOptions = errors.New("invalid QR code options")
+
+type QrCodeFormat string
+
+const (
+	QrCodeFormatPNG QrCodeFormat = "png"
+	QrCodeFormatSVG QrCodeFormat = "svg"
+)
+
+type QrCodeOptions struct {
+	Format        QrCodeFormat
+	Size          int
+	RecoveryLevel string
+}
+
 type QrCodeRepository interface {
 	Generate(text string) (url string, err error)
-}
\ No newline at end of file
+	Render(text string, options QrCodeOptions) (image []byte, contentType string, err error)
+	//Validate applies the defaults to the options and fails with ErrInvalidQrCodeOptions when they can't be rendered
+	Validate(options QrCodeOptions) (QrCodeOptions, error)
+}
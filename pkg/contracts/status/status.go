@@ -20,3 +20,38 @@ var PostValidateOwnerSuccess PostOperationStatus = 442
 var PostAlreadyReturned PostOperationStatus = 443
 var PostRequestValidationFailed PostOperationStatus = 444
 var PostRequestValidationSuccess PostOperationStatus = 445
+var PostValidationTokenExpired PostOperationStatus = 446
+var PostValidationTokenReplayed PostOperationStatus = 447
+var PostValidationTokenTampered PostOperationStatus = 448
+var PostQRCodeOptionsInvalid PostOperationStatus = 449
+
+var ClaimCreatedStatusSuccess PostOperationStatus = 551
+var ClaimCreatedStatusFailed PostOperationStatus = 552
+var ClaimAlreadyExists PostOperationStatus = 553
+var ClaimAnswersInvalid PostOperationStatus = 554
+var ClaimUpdatedStatusSuccess PostOperationStatus = 555
+var ClaimUpdatedStatusFailed PostOperationStatus = 556
+var ClaimAlreadyApproved PostOperationStatus = 557
+var ClaimNotApproved PostOperationStatus = 558
+var ClaimNotFound PostOperationStatus = 559
+var ClaimAlreadyDecided PostOperationStatus = 560
+
+var PostInvalidStateTransition PostOperationStatus = 661
+var PostStatusUpdatedSuccess PostOperationStatus = 662
+var PostStatusUpdatedFailed PostOperationStatus = 663
+
+var PostRestoredStatusSuccess PostOperationStatus = 771
+var PostRestoredStatusFailed PostOperationStatus = 772
+
+var PostHistoryFetchFailed PostOperationStatus = 881
+
+var PostImagesInvalid PostOperationStatus = 991
+var PostImagesUpdatedSuccess PostOperationStatus = 992
+var PostImagesUpdatedFailed PostOperationStatus = 993
+var PostImagesConflict PostOperationStatus = 994
+
+var PostUploadTooLarge PostOperationStatus = 1101
+var PostUploadInvalid PostOperationStatus = 1102
+var PostUploadMissing PostOperationStatus = 1103
+var PostUploadPresignedSuccess PostOperationStatus = 1104
+var PostUploadPresignedFailed PostOperationStatus = 1105
@@ -2,21 +2,45 @@ package contracts
 
 import (
 	"context"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"golek_posts_service/pkg/http/responses"
 	"mime/multipart"
+	"time"
 )
 
-type StorageRepository interface {
-	UploadFiles(files []*multipart.FileHeader, prefix string) ([]responses.S3Response, error)
-	UploadFile(file *multipart.FileHeader, prefix string) (responses.S3Response, error)
-	DeleteObject(objectKey *string) error
-	GetClient() (*s3.S3, error)
-	ReadFileBytes(file *multipart.FileHeader) ([]byte, error)
+type StoredObject struct {
+	Key         string
+	URL         string
+	ContentType string
+}
+
+type ObjectInfo struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
-type ICloudStorageRepo interface {
-	UploadFile(ctx context.Context, file *multipart.FileHeader, name string) (string, error)
-	DeleteFile(ctx context.Context, fileUrl string) error
+// PresignedUpload lets a client send the object straight to the storage backend
+type PresignedUpload struct {
+	Key       string            `json:"key"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// StorageRepository is implemented by every storage backend. Objects are addressed by
+// their key, urls are only derived from keys and never parsed back
+type StorageRepository interface {
+	UploadValidatorContract
+	Upload(ctx context.Context, data []byte, name string) (StoredObject, error)
+	UploadFile(ctx context.Context, file *multipart.FileHeader, name string) (StoredObject, error)
+	PresignUpload(ctx context.Context, key string, contentType string, ttl time.Duration) (PresignedUpload, error)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	Read(ctx context.Context, key string) ([]byte, error)
+	//List calls fn for every object whose key starts with prefix
+	List(ctx context.Context, prefix string, fn func(object ObjectInfo) error) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
 	Connect() error
+	Close() error
 }
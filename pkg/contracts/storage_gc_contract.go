@@ -0,0 +1,23 @@
+package contracts
+
+import "context"
+
+type MissingObject struct {
+	PostID string `json:"post_id"`
+	Key    string `json:"key"`
+}
+
+type StorageGCReport struct {
+	Scanned    int             `json:"scanned"`
+	Unmanaged  int             `json:"unmanaged"`
+	Referenced int             `json:"referenced"`
+	Orphans    []ObjectInfo    `json:"orphans"`
+	Deleted    int             `json:"deleted"`
+	Missing    []MissingObject `json:"missing"`
+}
+
+type StorageGCContract interface {
+	//Reconcile compares the stored objects with the image keys of the posts.
+	//Orphans are only removed when deleteOrphans is set
+	Reconcile(ctx context.Context, deleteOrphans bool) (StorageGCReport, error)
+}
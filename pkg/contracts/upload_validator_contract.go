@@ -0,0 +1,28 @@
+package contracts
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+)
+
+var (
+	ErrUploadTooLarge       = errors.New("uploaded file is too large")
+	ErrUploadTypeNotAllowed = errors.New("uploaded file type is not allowed")
+	ErrUploadCorrupted      = errors.New("uploaded file can not be decoded")
+	ErrUploadNotFound       = errors.New("uploaded file does not exist")
+)
+
+type UploadValidatorContract interface {
+	//Validate checks size, sniffed content type and decodability of the data
+	//and returns the detected content type
+	Validate(data []byte) (contentType string, err error)
+	//ValidateMeta checks a declared content type and size before the data is available
+	ValidateMeta(contentType string, size int64) error
+	//ReadUpload reads a multipart file without ever reading past the maximum size
+	ReadUpload(file *multipart.FileHeader) ([]byte, error)
+	//ReadLimited reads a stored object, failing with ErrUploadTooLarge once it passes the maximum size
+	ReadLimited(reader io.Reader) ([]byte, error)
+	//ObjectName derives a safe, unique object name from a caller supplied hint
+	ObjectName(hint string, contentType string) string
+}
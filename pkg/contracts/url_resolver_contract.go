@@ -0,0 +1,10 @@
+package contracts
+
+import "golek_posts_service/pkg/models"
+
+type URLResolverContract interface {
+	//Resolve maps a storage key to its public url, fallback is returned when there is no key
+	Resolve(key string, fallback string) string
+	//ResolvePost returns a copy of the post with every image url resolved
+	ResolvePost(post models.Post) models.Post
+}
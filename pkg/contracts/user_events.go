@@ -0,0 +1,33 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidUserEvent marks events which can never be applied, they go straight to the dead letter queue
+var ErrInvalidUserEvent = errors.New("invalid user event")
+
+type UserEventType string
+
+const (
+	UserEventUpdated UserEventType = "user.updated"
+	UserEventDeleted UserEventType = "user.deleted"
+)
+
+// UserEvent is published by the auth service whenever a profile changes
+type UserEvent struct {
+	ID         string        `json:"id"`
+	Type       UserEventType `json:"type"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	User       struct {
+		ID        int64  `json:"id"`
+		Username  string `json:"username"`
+		UserMajor string `json:"usermajor"`
+	} `json:"user"`
+}
+
+type UserSyncContract interface {
+	HandleUserEvent(ctx context.Context, event UserEvent) error
+}
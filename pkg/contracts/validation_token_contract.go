@@ -0,0 +1,21 @@
+package contracts
+
+import "errors"
+
+var (
+	ErrValidationTokenExpired  = errors.New("validation token has expired")
+	ErrValidationTokenReplayed = errors.New("validation token has already been used")
+	ErrValidationTokenTampered = errors.New("validation token signature is invalid")
+)
+
+type ValidationClaims struct {
+	PostID    string `json:"pid"`
+	IssuedBy  string `json:"uid"`
+	Nonce     string `json:"nonce"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type ValidationTokenContract interface {
+	Issue(postID string, issuedBy string) (token string, claims ValidationClaims, err error)
+	Verify(token string) (ValidationClaims, error)
+}
@@ -5,11 +5,18 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"golek_posts_service/pkg/models"
 	"log"
 )
 
 func (m Migration) MigrateSettings() {
 	m.CreateIndexes()
+	m.BackfillPostType()
+	m.BackfillPostStatus()
+	m.BackfillPostImages()
+	m.CreateClaimIndexes()
+	m.CreateAuditIndexes()
+	m.CreateOutboxIndexes()
 	log.Println("Migrates Settings Success")
 }
 
@@ -35,4 +42,190 @@ func (m Migration) CreateIndexes() {
 	if err != nil {
 		panic(err)
 	}
+
+	_, err = m.DB.GetCollection().Indexes().CreateOne(context.Background(),
+		mongo.IndexModel{Keys: bson.D{{"type", 1}, {"status", 1}}},
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = m.DB.GetCollection().Indexes().CreateOne(context.Background(),
+		mongo.IndexModel{Keys: bson.D{{"deleted_at", 1}}},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// BackfillPostType marks posts created before lost posts existed as found
+func (m Migration) BackfillPostType() {
+
+	_, err := m.DB.GetCollection().UpdateMany(context.Background(),
+		bson.D{{"type", bson.D{{"$exists", false}}}},
+		bson.D{{"$set", bson.D{{"type", "found"}}}},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// BackfillPostStatus derives the lifecycle status of posts stored before it was tracked
+func (m Migration) BackfillPostStatus() {
+
+	_, err := m.DB.GetCollection().UpdateMany(context.Background(),
+		bson.D{{"status", bson.D{{"$exists", false}}}, {"is_returned", true}},
+		bson.D{{"$set", bson.D{{"status", "returned"}}}},
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = m.DB.GetCollection().UpdateMany(context.Background(),
+		bson.D{{"status", bson.D{{"$exists", false}}}},
+		bson.D{{"$set", bson.D{{"status", "open"}}}},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// BackfillPostImages turns the single image of older posts into the first entry of the gallery
+func (m Migration) BackfillPostImages() {
+
+	_, err := m.DB.GetCollection().UpdateMany(context.Background(),
+		bson.D{{"images", bson.D{{"$exists", false}}}, {"image_url", bson.D{{"$nin", bson.A{nil, ""}}}}},
+		mongo.Pipeline{
+			{{"$set", bson.D{
+				{"images", bson.A{bson.D{
+					{"id", bson.D{{"$toString", "$_id"}}},
+					{"url", "$image_url"},
+					{"key", "$image_key"},
+					{"order", 0},
+				}}},
+				{"cover_image_id", bson.D{{"$toString", "$_id"}}},
+			}}},
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// BackfillPostImageKeys stores the storage keys of images which were saved with their url only
+func (m Migration) BackfillPostImageKeys(keyFromURL func(fileUrl string) (string, bool)) {
+
+	ctx := context.Background()
+
+	cursor, err := m.DB.GetCollection().Find(ctx, bson.D{
+		{"image_url", bson.D{{"$nin", bson.A{nil, ""}}}},
+		{"$or", bson.A{
+			bson.D{{"image_key", bson.D{{"$in", bson.A{nil, ""}}}}},
+			bson.D{{"images.key", bson.D{{"$in", bson.A{nil, ""}}}}},
+		}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var post models.Post
+		if err := cursor.Decode(&post); err != nil {
+			panic(err)
+		}
+
+		resolve := func(fileUrl string, key string) string {
+			if key != "" || fileUrl == "" {
+				return key
+			}
+			resolved, _ := keyFromURL(fileUrl)
+			return resolved
+		}
+
+		for i, image := range post.Images {
+			post.Images[i].Key = resolve(image.URL, image.Key)
+			post.Images[i].MediumKey = resolve(image.MediumURL, image.MediumKey)
+			post.Images[i].SmallKey = resolve(image.SmallURL, image.SmallKey)
+		}
+
+		set := bson.D{{"image_key", resolve(post.ImageURL, post.ImageKey)}}
+		if len(post.Images) > 0 {
+			set = append(set, bson.E{Key: "images", Value: post.Images})
+		}
+
+		_, err := m.DB.GetCollection().UpdateByID(ctx, post.ID, bson.D{{"$set", set}})
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		panic(err)
+	}
+}
+
+func (m Migration) CreateClaimIndexes() {
+
+	_, err := m.DB.GetClaimCollection().Indexes().CreateOne(context.Background(),
+		mongo.IndexModel{Keys: bson.D{{"post_id", 1}, {"claimant_id", 1}, {"status", 1}}},
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	//Only one claim per post can be approved, concurrent approvals can't both win
+	_, err = m.DB.GetClaimCollection().Indexes().CreateOne(context.Background(),
+		mongo.IndexModel{
+			Keys: bson.D{{"post_id", 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.D{{"status", models.ClaimStatusApproved}}),
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (m Migration) CreateAuditIndexes() {
+
+	_, err := m.DB.GetAuditCollection().Indexes().CreateOne(context.Background(),
+		mongo.IndexModel{Keys: bson.D{{"post_id", 1}, {"created_at", -1}}},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (m Migration) CreateOutboxIndexes() {
+
+	_, err := m.DB.GetOutboxCollection().Indexes().CreateOne(context.Background(),
+		mongo.IndexModel{Keys: bson.D{{"status", 1}, {"next_attempt_at", 1}}},
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	//Delivered events are only kept for a week
+	_, err = m.DB.GetOutboxCollection().Indexes().CreateOne(context.Background(),
+		mongo.IndexModel{
+			Keys:    bson.D{{"delivered_at", 1}},
+			Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60),
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	//Failed events are kept a month for inspection, then they expire too
+	_, err = m.DB.GetOutboxCollection().Indexes().CreateOne(context.Background(),
+		mongo.IndexModel{
+			Keys:    bson.D{{"failed_at", 1}},
+			Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60),
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
 }
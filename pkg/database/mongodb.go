@@ -10,14 +10,17 @@ import (
 )
 
 type Database struct {
-	DbUsername   string
-	DBPassword   string
-	DbName       string
-	DbHost       string
-	DbPort       string
-	DbCollection string
-	collection   *mongo.Collection
-	connection   *mongo.Database
+	DbUsername         string
+	DBPassword         string
+	DbName             string
+	DbHost             string
+	DbPort             string
+	DbCollection       string
+	DbClaimCollection  string
+	DbAuditCollection  string
+	DbOutboxCollection string
+	collection         *mongo.Collection
+	connection         *mongo.Database
 }
 
 func (db *Database) Prepare() contracts.MongoDBContract {
@@ -68,6 +71,26 @@ func (db *Database) GetCollection() *mongo.Collection {
 	return db.connection.Collection(db.DbCollection)
 }
 
+func (db *Database) GetClaimCollection() *mongo.Collection {
+	return db.connection.Collection(db.DbClaimCollection)
+}
+
+func (db *Database) GetAuditCollection() *mongo.Collection {
+	return db.connection.Collection(db.DbAuditCollection)
+}
+
+func (db *Database) GetOutboxCollection() *mongo.Collection {
+	return db.connection.Collection(db.DbOutboxCollection)
+}
+
+// Disconnect closes the client, pending operations are given until the context is done
+func (db *Database) Disconnect(ctx context.Context) error {
+	if db.connection == nil {
+		return nil
+	}
+	return db.connection.Client().Disconnect(ctx)
+}
+
 func (db *Database) Dsn() string {
 	//return fmt.Sprintf("mongodb://%s:%s@%s:%s/%s?", db.DbUsername, db.DBPassword, db.DbHost, db.DbPort, db.DbName)
 	return fmt.Sprintf("mongodb://%s:%s@%s:%s/%s?authSource=admin&ssl=false", db.DbUsername, db.DBPassword, db.DbHost, db.DbPort, db.DbName)
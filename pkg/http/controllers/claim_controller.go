@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/requests"
+	"golek_posts_service/pkg/http/responses"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ClaimHandler struct {
+	ClaimService contracts.ClaimServiceContract
+}
+
+func (h *ClaimHandler) Create(c *gin.Context) {
+
+	var createReq requests.CreateClaimRequest
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	err := c.ShouldBind(&createReq)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, responses.HttpErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Binding error " + err.Error(),
+		})
+		return
+	}
+
+	claim, opStatus, err := h.ClaimService.Submit(authContext, c.Param("id"), createReq)
+	if err != nil {
+		abortWithClaimError(c, "Submit Claim ", opStatus, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, responses.HttpResponse{
+		StatusCode: http.StatusCreated,
+		Message:    "Claim submitted successfully",
+		Data:       claim,
+	})
+}
+
+func (h *ClaimHandler) Fetch(c *gin.Context) {
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	claims, opStatus, err := h.ClaimService.ListByPost(authContext, c.Param("id"))
+	if err != nil {
+		abortWithClaimError(c, "List Claims ", opStatus, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.HttpResponse{
+		StatusCode: http.StatusOK,
+		Data:       claims,
+	})
+}
+
+func (h *ClaimHandler) Approve(c *gin.Context) {
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	claim, opStatus, err := h.ClaimService.Approve(authContext, c.Param("id"), c.Param("claim_id"))
+	if err != nil {
+		abortWithClaimError(c, "Approve Claim ", opStatus, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.HttpResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Claim approved",
+		Data:       claim,
+	})
+}
+
+func (h *ClaimHandler) Reject(c *gin.Context) {
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	claim, opStatus, err := h.ClaimService.Reject(authContext, c.Param("id"), c.Param("claim_id"))
+	if err != nil {
+		abortWithClaimError(c, "Reject Claim ", opStatus, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.HttpResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Claim rejected",
+		Data:       claim,
+	})
+}
+
+func abortWithClaimError(c *gin.Context, prefix string, opStatus status.PostOperationStatus, err error) {
+
+	code := http.StatusInternalServerError
+
+	switch {
+	case opStatus == status.OperationUnauthorized:
+		code = http.StatusUnauthorized
+	case opStatus == status.OperationForbidden:
+		code = http.StatusForbidden
+	case opStatus == status.ClaimNotFound || err == mongo.ErrNoDocuments:
+		code = http.StatusNotFound
+	case opStatus == status.ClaimAnswersInvalid:
+		code = http.StatusUnprocessableEntity
+	case opStatus == status.ClaimAlreadyExists || opStatus == status.ClaimAlreadyApproved || opStatus == status.ClaimAlreadyDecided ||
+		opStatus == status.PostInvalidStateTransition:
+		code = http.StatusConflict
+	}
+
+	c.JSON(code, responses.HttpErrorResponse{
+		StatusCode: code,
+		Error:      prefix + err.Error(),
+	})
+}
@@ -8,9 +8,10 @@ import (
 	"net/http"
 )
 
-func SetupHandler(router *gin.Engine, postService *contracts.PostServiceContract) {
+func SetupHandler(router *gin.Engine, postService *contracts.PostServiceContract, claimService *contracts.ClaimServiceContract, urlResolver *contracts.URLResolverContract) {
 
-	postHandler := PostHandler{*postService}
+	postHandler := PostHandler{*postService, *urlResolver}
+	claimHandler := ClaimHandler{*claimService}
 
 	//router.Use(middleware.HandleCORS())
 
@@ -34,11 +35,27 @@ func SetupHandler(router *gin.Engine, postService *contracts.PostServiceContract
 	//r.Use(middleware.ValidateRequestHeaderMiddleware)
 	r.GET("/list", middleware.ValidateRequestHeaderMiddleware, postHandler.Fetch)
 	r.GET("/:id", middleware.ValidateRequestHeaderMiddleware, postHandler.FetchByID)
+	r.GET("/:id/matches", middleware.ValidateRequestHeaderMiddleware, postHandler.FetchMatches)
+	r.GET("/:id/history", middleware.ValidateRequestHeaderMiddleware, postHandler.History)
 	r.GET("/s/:keyword", middleware.ValidateRequestHeaderMiddleware, postHandler.Search)
 	r.POST("/", middleware.ValidateRequestHeaderMiddleware, postHandler.Create)
+	r.POST("/uploads", middleware.ValidateRequestHeaderMiddleware, postHandler.PresignUploads)
 	r.PUT("/:id", middleware.ValidateRequestHeaderMiddleware, postHandler.Update)
 	r.DELETE("/:id", middleware.ValidateRequestHeaderMiddleware, postHandler.Delete)
+	r.POST("/:id/restore", middleware.ValidateRequestHeaderMiddleware, postHandler.Restore)
+	r.PUT("/:id/archive", middleware.ValidateRequestHeaderMiddleware, postHandler.Archive)
+	r.POST("/:id/images", middleware.ValidateRequestHeaderMiddleware, postHandler.AddImages)
+	r.PUT("/:id/images/order", middleware.ValidateRequestHeaderMiddleware, postHandler.ReorderImages)
+	r.PUT("/:id/images/:image_id/cover", middleware.ValidateRequestHeaderMiddleware, postHandler.SetCoverImage)
+	r.DELETE("/:id/images/:image_id", middleware.ValidateRequestHeaderMiddleware, postHandler.RemoveImage)
 	r.GET("/validate/:post_id", middleware.ValidateRequestHeaderMiddleware, postHandler.ReqValidateOwner)
+	r.GET("/validate/:post_id/qr.png", middleware.ValidateRequestHeaderMiddleware, postHandler.ReqValidateOwnerQRCode)
+	r.GET("/validate/:post_id/qr.svg", middleware.ValidateRequestHeaderMiddleware, postHandler.ReqValidateOwnerQRCode)
 	r.POST("/validate/", middleware.ValidateRequestHeaderMiddleware, postHandler.ValidateOwner)
 
+	r.POST("/:id/claims", middleware.ValidateRequestHeaderMiddleware, claimHandler.Create)
+	r.GET("/:id/claims", middleware.ValidateRequestHeaderMiddleware, claimHandler.Fetch)
+	r.PUT("/:id/claims/:claim_id/approve", middleware.ValidateRequestHeaderMiddleware, claimHandler.Approve)
+	r.PUT("/:id/claims/:claim_id/reject", middleware.ValidateRequestHeaderMiddleware, claimHandler.Reject)
+
 }
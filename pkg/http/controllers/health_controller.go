@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/http/responses"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	Checks map[string]contracts.HealthChecker
+}
+
+// Health always answers 200 while the service can serve requests. Unhealthy dependencies
+// only degrade it, e.g. events wait in the outbox until the broker is back
+func (h *HealthHandler) Health(c *gin.Context) {
+
+	names := make([]string, 0, len(h.Checks))
+	for name := range h.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	overall := "ok"
+	checks := make(map[string]string, len(names))
+	for _, name := range names {
+		checks[name] = "up"
+		if !h.Checks[name].Healthy() {
+			checks[name] = "down"
+			overall = "degraded"
+		}
+	}
+
+	c.JSON(http.StatusOK, responses.HttpResponse{
+		StatusCode: http.StatusOK,
+		Message:    overall,
+		Data:       checks,
+	})
+}
+
+func SetupHealthHandler(router *gin.Engine, checks map[string]contracts.HealthChecker) {
+
+	healthHandler := HealthHandler{Checks: checks}
+
+	router.GET("/health", healthHandler.Health)
+}
@@ -10,6 +10,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -17,6 +18,7 @@ import (
 
 type PostHandler struct {
 	PostService contracts.PostServiceContract
+	URLResolver contracts.URLResolverContract
 }
 
 func (h *PostHandler) Fetch(c *gin.Context) {
@@ -80,6 +82,30 @@ func (h *PostHandler) Fetch(c *gin.Context) {
 	if userIDint != 0 {
 		filter["user_id"] = userIDint
 	}
+
+	//An explicit lifecycle status replaces the default returned filter
+	if postStatus, ok := c.GetQuery("status"); ok && postStatus != "" {
+		if !models.PostStatus(postStatus).IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Status Parameter " + postStatus + " is not a valid post status",
+			})
+			return
+		}
+		filter["status"] = postStatus
+		if _, ok := c.GetQuery("returned"); !ok {
+			delete(filter, "is_returned")
+		}
+	}
+
+	if postType, ok := c.GetQuery("type"); ok && postType != "" {
+		if postType != string(models.PostTypeLost) && postType != string(models.PostTypeFound) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Type Parameter must be lost or found",
+			})
+			return
+		}
+		filter["type"] = postType
+	}
 	posts, err := h.PostService.Fetch(context.TODO(), paginate, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -90,7 +116,7 @@ func (h *PostHandler) Fetch(c *gin.Context) {
 
 	c.JSON(http.StatusOK, responses.HttpPaginationResponse{
 		HttpResponse: responses.HttpResponse{
-			Data:       posts,
+			Data:       h.resolvePosts(posts),
 			StatusCode: 200,
 		},
 		PerPage: paginate.PerPage,
@@ -117,11 +143,45 @@ func (h *PostHandler) FetchByID(c *gin.Context) {
 	c.JSON(http.StatusOK, responses.HttpResponse{
 		StatusCode: http.StatusOK,
 		Message:    "data exists",
-		Data:       post,
+		Data:       h.URLResolver.ResolvePost(post),
 	})
 	return
 }
 
+func (h *PostHandler) FetchMatches(c *gin.Context) {
+
+	limit := 10
+	if qLimit, ok := c.GetQuery("limit"); ok {
+		parsed, err := strconv.Atoi(qLimit)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Parsing Limit Parameter " + err.Error(),
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	matches, err := h.PostService.FindMatches(context.Background(), c.Param("id"), limit)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "PostService FindMatches " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.HttpResponse{
+		StatusCode: http.StatusOK,
+		Data:       h.resolveMatches(matches),
+	})
+}
+
 func (h *PostHandler) Search(c *gin.Context) {
 
 	page, ok := c.GetQuery("page")
@@ -169,7 +229,7 @@ func (h *PostHandler) Search(c *gin.Context) {
 		HttpResponse: responses.HttpResponse{
 			StatusCode: http.StatusOK,
 			Message:    "",
-			Data:       posts,
+			Data:       h.resolvePosts(posts),
 		},
 	})
 	return
@@ -192,6 +252,41 @@ func (h *PostHandler) Create(c *gin.Context) {
 	}
 
 	createdPost, opStatus, err := h.PostService.Create(authContext, createReq)
+	if opStatus == status.PostImagesInvalid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "PostService Create " + err.Error(),
+		})
+		return
+	}
+
+	if opStatus == status.PostUploadTooLarge {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "PostService Create " + err.Error(),
+		})
+		return
+	}
+
+	if opStatus == status.PostUploadInvalid {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": "PostService Create " + err.Error(),
+		})
+		return
+	}
+
+	if opStatus == status.PostUploadMissing {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "PostService Create " + err.Error(),
+		})
+		return
+	}
+
+	if opStatus == status.OperationForbidden {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "PostService Create " + err.Error(),
+		})
+		return
+	}
+
 	if err != nil || opStatus == status.PostCreatedStatusFailed {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "PostService Create " + err.Error(),
@@ -201,7 +296,7 @@ func (h *PostHandler) Create(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Post created successfully",
-		"data":    createdPost,
+		"data":    h.URLResolver.ResolvePost(createdPost),
 	})
 	return
 }
@@ -237,6 +332,34 @@ func (h *PostHandler) Update(c *gin.Context) {
 		return
 	}
 
+	if opStatus == status.PostImagesInvalid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "PostService Update " + err.Error(),
+		})
+		return
+	}
+
+	if opStatus == status.PostUploadTooLarge {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "PostService Update " + err.Error(),
+		})
+		return
+	}
+
+	if opStatus == status.PostUploadInvalid {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": "PostService Update " + err.Error(),
+		})
+		return
+	}
+
+	if opStatus == status.PostImagesConflict || opStatus == status.PostInvalidStateTransition {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "PostService Update " + err.Error(),
+		})
+		return
+	}
+
 	if err != nil && opStatus == status.PostUpdatedStatusFailed {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "PostService Update " + err.Error(),
@@ -246,7 +369,7 @@ func (h *PostHandler) Update(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Post updated successfully",
-		"data":    updatedPost,
+		"data":    h.URLResolver.ResolvePost(updatedPost),
 	})
 	return
 }
@@ -294,6 +417,166 @@ func (h *PostHandler) Delete(c *gin.Context) {
 	return
 }
 
+func (h *PostHandler) Restore(c *gin.Context) {
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	opStatus, err := h.PostService.Restore(authContext, c.Param("id"))
+
+	if opStatus == status.OperationUnauthorized {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "PostService Restore " + err.Error(),
+		})
+		return
+	}
+
+	if opStatus == status.OperationForbidden {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "PostService Restore " + err.Error(),
+		})
+		return
+	}
+
+	if err != nil || opStatus == status.PostRestoredStatusFailed {
+
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.HttpResponse{
+		StatusCode: http.StatusOK,
+		Message:    "data restored successfully",
+		Data:       nil,
+	})
+}
+
+func (h *PostHandler) History(c *gin.Context) {
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Parsing Page Parameter " + err.Error(),
+		})
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "25"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Parsing Limit Parameter " + err.Error(),
+		})
+		return
+	}
+
+	paginate := models.Pagination{
+		Page:    page,
+		PerPage: limit,
+	}
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	audits, opStatus, err := h.PostService.History(authContext, c.Param("id"), paginate)
+
+	if opStatus == status.OperationUnauthorized {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "PostService History " + err.Error(),
+		})
+		return
+	}
+
+	if opStatus == status.OperationForbidden {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "PostService History " + err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "PostService History " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.HttpPaginationResponse{
+		HttpResponse: responses.HttpResponse{
+			StatusCode: http.StatusOK,
+			Data:       audits,
+		},
+		PerPage: paginate.PerPage,
+		Page:    paginate.Page,
+	})
+}
+
+func (h *PostHandler) Archive(c *gin.Context) {
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	opStatus, err := h.PostService.Archive(authContext, c.Param("id"))
+
+	if opStatus == status.OperationUnauthorized {
+		c.JSON(http.StatusUnauthorized, responses.HttpErrorResponse{
+			StatusCode: http.StatusUnauthorized,
+			Error:      "PostService Archive " + err.Error(),
+		})
+		return
+	}
+
+	if opStatus == status.OperationForbidden {
+		c.JSON(http.StatusForbidden, responses.HttpErrorResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      "PostService Archive " + err.Error(),
+		})
+		return
+	}
+
+	if opStatus == status.PostInvalidStateTransition {
+		c.JSON(http.StatusConflict, responses.HttpErrorResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "PostService Archive " + err.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, responses.HttpErrorResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.HttpErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "PostService Archive " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.HttpResponse{
+		StatusCode: http.StatusOK,
+		Message:    "post archived",
+		Data:       nil,
+	})
+}
+
 func (h *PostHandler) ReqValidateOwner(c *gin.Context) {
 
 	postID := c.Param("post_id")
@@ -310,6 +593,20 @@ func (h *PostHandler) ReqValidateOwner(c *gin.Context) {
 	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
 
 	qrCodeUrl, opStatus, err := h.PostService.RequestValidateOwner(authContext, postID)
+	if opStatus == status.ClaimNotApproved {
+		c.JSON(http.StatusForbidden, responses.HttpErrorResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      "Request Validate Owner " + err.Error(),
+		})
+		return
+	}
+	if opStatus == status.PostInvalidStateTransition {
+		c.JSON(http.StatusConflict, responses.HttpErrorResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "Request Validate Owner " + err.Error(),
+		})
+		return
+	}
 	if opStatus == status.OperationUnauthorized {
 		c.JSON(http.StatusUnauthorized, responses.HttpErrorResponse{
 			StatusCode: http.StatusUnauthorized,
@@ -350,6 +647,105 @@ func (h *PostHandler) ReqValidateOwner(c *gin.Context) {
 	return
 }
 
+func (h *PostHandler) ReqValidateOwnerQRCode(c *gin.Context) {
+
+	postID := c.Param("post_id")
+	if postID == "" {
+		c.JSON(http.StatusBadRequest, responses.HttpErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Query parameter is not valid",
+			Data:       nil,
+		})
+		return
+	}
+
+	options := contracts.QrCodeOptions{
+		Format:        contracts.QrCodeFormatPNG,
+		RecoveryLevel: c.Query("level"),
+	}
+
+	if strings.HasSuffix(c.FullPath(), ".svg") {
+		options.Format = contracts.QrCodeFormatSVG
+	}
+
+	if size, ok := c.GetQuery("size"); ok {
+		qSize, err := strconv.Atoi(size)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.HttpErrorResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      "Parsing Size Parameter " + err.Error(),
+			})
+			return
+		}
+		options.Size = qSize
+	}
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	image, contentType, opStatus, err := h.PostService.RenderValidationQRCode(authContext, postID, options)
+	if opStatus == status.PostQRCodeOptionsInvalid {
+		c.JSON(http.StatusBadRequest, responses.HttpErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Request Validate Owner " + err.Error(),
+		})
+		return
+	}
+	if opStatus == status.ClaimNotApproved {
+		c.JSON(http.StatusForbidden, responses.HttpErrorResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      "Request Validate Owner " + err.Error(),
+		})
+		return
+	}
+	if opStatus == status.PostInvalidStateTransition {
+		c.JSON(http.StatusConflict, responses.HttpErrorResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "Request Validate Owner " + err.Error(),
+		})
+		return
+	}
+	if opStatus == status.OperationUnauthorized {
+		c.JSON(http.StatusUnauthorized, responses.HttpErrorResponse{
+			StatusCode: http.StatusUnauthorized,
+			Error:      "Request Validate Owner " + err.Error(),
+		})
+		return
+	}
+	if opStatus == status.OperationForbidden {
+		c.JSON(http.StatusForbidden, responses.HttpErrorResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      "Request Validate Owner " + err.Error(),
+		})
+		return
+	}
+	if opStatus == status.PostAlreadyReturned {
+		c.JSON(http.StatusConflict, responses.HttpErrorResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "post already returned",
+		})
+		return
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, responses.HttpErrorResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.HttpErrorResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Request Validate Owner " + err.Error(),
+		})
+		return
+	}
+
+	//The code embeds a confirmation key, it must never be cached
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, contentType, image)
+}
+
 func (h *PostHandler) ValidateOwner(c *gin.Context) {
 
 	var validatePostReq requests.ValidateItemOwnerRequest
@@ -389,6 +785,41 @@ func (h *PostHandler) ValidateOwner(c *gin.Context) {
 			})
 			return
 		}
+		if opStatus == status.PostValidationTokenExpired {
+			c.JSON(http.StatusGone, responses.HttpErrorResponse{
+				StatusCode: http.StatusGone,
+				Error:      "Validate Owner " + err.Error(),
+			})
+			return
+		}
+		if opStatus == status.PostValidationTokenReplayed {
+			c.JSON(http.StatusConflict, responses.HttpErrorResponse{
+				StatusCode: http.StatusConflict,
+				Error:      "Validate Owner " + err.Error(),
+			})
+			return
+		}
+		if opStatus == status.ClaimNotApproved {
+			c.JSON(http.StatusForbidden, responses.HttpErrorResponse{
+				StatusCode: http.StatusForbidden,
+				Error:      "Validate Owner " + err.Error(),
+			})
+			return
+		}
+		if opStatus == status.PostInvalidStateTransition {
+			c.JSON(http.StatusConflict, responses.HttpErrorResponse{
+				StatusCode: http.StatusConflict,
+				Error:      "Validate Owner " + err.Error(),
+			})
+			return
+		}
+		if opStatus == status.PostValidationTokenTampered {
+			c.JSON(http.StatusBadRequest, responses.HttpErrorResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      "Validate Owner " + err.Error(),
+			})
+			return
+		}
 	}
 
 	if opStatus == status.PostAlreadyReturned {
@@ -405,3 +836,20 @@ func (h *PostHandler) ValidateOwner(c *gin.Context) {
 		})
 	}
 }
+
+// resolvePosts rewrites the image urls of the posts before they are serialized
+func (h *PostHandler) resolvePosts(posts []models.Post) []models.Post {
+	resolved := make([]models.Post, len(posts))
+	for i, post := range posts {
+		resolved[i] = h.URLResolver.ResolvePost(post)
+	}
+	return resolved
+}
+
+func (h *PostHandler) resolveMatches(matches []models.PostMatch) []models.PostMatch {
+	resolved := make([]models.PostMatch, len(matches))
+	for i, match := range matches {
+		resolved[i] = models.PostMatch{Post: h.URLResolver.ResolvePost(match.Post), Score: match.Score}
+	}
+	return resolved
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/requests"
+	"golek_posts_service/pkg/http/responses"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (h *PostHandler) AddImages(c *gin.Context) {
+
+	var imagesReq requests.AddPostImagesRequest
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	if err := c.ShouldBind(&imagesReq); err != nil {
+		c.JSON(http.StatusBadRequest, responses.HttpErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Binding error " + err.Error(),
+		})
+		return
+	}
+
+	post, opStatus, err := h.PostService.AddImages(authContext, c.Param("id"), imagesReq)
+	if err != nil {
+		abortWithImageError(c, "PostService AddImages ", opStatus, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.HttpResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Images added",
+		Data:       h.URLResolver.ResolvePost(post),
+	})
+}
+
+func (h *PostHandler) RemoveImage(c *gin.Context) {
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	post, opStatus, err := h.PostService.RemoveImage(authContext, c.Param("id"), c.Param("image_id"))
+	if err != nil {
+		abortWithImageError(c, "PostService RemoveImage ", opStatus, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.HttpResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Image removed",
+		Data:       h.URLResolver.ResolvePost(post),
+	})
+}
+
+func (h *PostHandler) ReorderImages(c *gin.Context) {
+
+	var orderReq requests.ReorderPostImagesRequest
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	if err := c.ShouldBindJSON(&orderReq); err != nil {
+		c.JSON(http.StatusBadRequest, responses.HttpErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Binding error " + err.Error(),
+		})
+		return
+	}
+
+	post, opStatus, err := h.PostService.ReorderImages(authContext, c.Param("id"), orderReq)
+	if err != nil {
+		abortWithImageError(c, "PostService ReorderImages ", opStatus, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.HttpResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Images reordered",
+		Data:       h.URLResolver.ResolvePost(post),
+	})
+}
+
+func (h *PostHandler) SetCoverImage(c *gin.Context) {
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	post, opStatus, err := h.PostService.SetCoverImage(authContext, c.Param("id"), c.Param("image_id"))
+	if err != nil {
+		abortWithImageError(c, "PostService SetCoverImage ", opStatus, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.HttpResponse{
+		StatusCode: http.StatusOK,
+		Message:    "Cover image updated",
+		Data:       h.URLResolver.ResolvePost(post),
+	})
+}
+
+func (h *PostHandler) PresignUploads(c *gin.Context) {
+
+	var presignReq requests.PresignUploadRequest
+
+	val, _ := c.Get("authenticatedRequest")
+	authContext := context.WithValue(context.Background(), "authenticatedRequest", val)
+
+	if err := c.ShouldBindJSON(&presignReq); err != nil {
+		c.JSON(http.StatusBadRequest, responses.HttpErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Binding error " + err.Error(),
+		})
+		return
+	}
+
+	uploads, opStatus, err := h.PostService.PresignUploads(authContext, presignReq)
+	if err != nil {
+		abortWithImageError(c, "PostService PresignUploads ", opStatus, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, responses.HttpResponse{
+		StatusCode: http.StatusCreated,
+		Message:    "Upload urls created",
+		Data:       uploads,
+	})
+}
+
+func abortWithImageError(c *gin.Context, prefix string, opStatus status.PostOperationStatus, err error) {
+
+	code := http.StatusInternalServerError
+
+	switch {
+	case opStatus == status.OperationUnauthorized:
+		code = http.StatusUnauthorized
+	case opStatus == status.OperationForbidden:
+		code = http.StatusForbidden
+	case err == mongo.ErrNoDocuments:
+		code = http.StatusNotFound
+	case opStatus == status.PostImagesInvalid:
+		code = http.StatusBadRequest
+	case opStatus == status.PostImagesConflict, opStatus == status.PostInvalidStateTransition:
+		code = http.StatusConflict
+	case opStatus == status.PostUploadTooLarge:
+		code = http.StatusRequestEntityTooLarge
+	case opStatus == status.PostUploadInvalid:
+		code = http.StatusUnsupportedMediaType
+	case opStatus == status.PostUploadMissing:
+		code = http.StatusUnprocessableEntity
+	}
+
+	c.JSON(code, responses.HttpErrorResponse{
+		StatusCode: code,
+		Error:      prefix + err.Error(),
+	})
+}
@@ -0,0 +1,10 @@
+package requests
+
+type CreateClaimRequest struct {
+	Answers []ClaimAnswerRequest `binding:"required,dive" form:"answers" json:"answers"`
+	Message string               `binding:"" form:"message" json:"message"`
+}
+
+type ClaimAnswerRequest struct {
+	Answer string `binding:"required" form:"answer" json:"answer"`
+}
@@ -5,7 +5,10 @@ import "mime/multipart"
 type CreatePostRequest struct {
 	//UserID          int64                       `binding:"required" form:"user_id" json:"user_id"`
 	Title           string                      `binding:"required" form:"title"`
-	Image           *multipart.FileHeader       `binding:"required" form:"image" `
+	Type            string                      `binding:"omitempty,oneof=lost found" form:"type"`
+	Image           *multipart.FileHeader       `binding:"" form:"image"`
+	Images          []*multipart.FileHeader     `binding:"" form:"images"`
+	UploadKeys      []string                    `binding:"" form:"upload_keys"`
 	Place           string                      `binding:"required" form:"place"`
 	Description     string                      `binding:"" form:"description"`
 	Characteristics []PostCharacteristicRequest `binding:"required" form:"characteristics"`
@@ -23,9 +26,23 @@ type UpdatePostRequest struct {
 
 type ValidateItemOwnerRequest struct {
 	PostID string `json:"post_id" binding:"required"`
-	Hash   string `json:"hash" binding:"required"`
+	Token  string `json:"token" binding:"required"`
 }
 
 type PostCharacteristicRequest struct {
 	Title string `binding:"required" form:"title" json:"title"`
 }
+
+type AddPostImagesRequest struct {
+	Images []*multipart.FileHeader `binding:"required" form:"images"`
+}
+
+type ReorderPostImagesRequest struct {
+	ImageIDs []string `binding:"required" json:"image_ids"`
+}
+
+type PresignUploadRequest struct {
+	ContentType string `binding:"required" json:"content_type"`
+	Size        int64  `binding:"required,min=1" json:"size"`
+	Count       int    `binding:"omitempty,min=1" json:"count"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditOperation string
+
+const (
+	AuditOperationCreate   AuditOperation = "create"
+	AuditOperationUpdate   AuditOperation = "update"
+	AuditOperationDelete   AuditOperation = "delete"
+	AuditOperationRestore  AuditOperation = "restore"
+	AuditOperationValidate AuditOperation = "validate"
+	AuditOperationArchive  AuditOperation = "archive"
+)
+
+type FieldChange struct {
+	Field string `bson:"field" json:"field"`
+	Old   any    `bson:"old" json:"old"`
+	New   any    `bson:"new" json:"new"`
+}
+
+type PostAudit struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	PostID    primitive.ObjectID `json:"post_id" bson:"post_id"`
+	Operation AuditOperation     `json:"operation" bson:"operation"`
+	ActorID   int64              `json:"actor_id" bson:"actor_id"`
+	ActorRole string             `json:"actor_role" bson:"actor_role"`
+	Changes   []FieldChange      `json:"changes" bson:"changes"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ClaimStatus string
+
+const (
+	ClaimStatusPending  ClaimStatus = "pending"
+	ClaimStatusApproved ClaimStatus = "approved"
+	ClaimStatusRejected ClaimStatus = "rejected"
+)
+
+type ClaimAnswer struct {
+	Characteristic string `bson:"characteristic" json:"characteristic"`
+	Answer         string `bson:"answer" json:"answer"`
+}
+
+type Claim struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id"`
+	PostID     primitive.ObjectID `json:"post_id" bson:"post_id"`
+	ClaimantID int64              `json:"claimant_id" bson:"claimant_id"`
+	Claimant   UserInfo           `json:"claimant" bson:"claimant"`
+	Answers    []ClaimAnswer      `json:"answers" bson:"answers"`
+	Message    string             `json:"message,omitempty" bson:"message"`
+	Status     ClaimStatus        `json:"status" bson:"status"`
+	DecidedBy  int64              `json:"decided_by,omitempty" bson:"decided_by"`
+	DecidedAt  *time.Time         `json:"decided_at,omitempty" bson:"decided_at"`
+	UpdatedAt  *time.Time         `json:"updated_at,omitempty" bson:"updated_at"`
+	CreatedAt  *time.Time         `json:"created_at,omitempty" bson:"created_at"`
+}
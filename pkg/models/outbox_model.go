@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "pending"
+	OutboxStatusDelivered OutboxStatus = "delivered"
+	OutboxStatusFailed    OutboxStatus = "failed"
+)
+
+// OutboxEvent is a message waiting to be published to the broker. It is stored together
+// with the post change which caused it, so the message is never lost when the broker is down
+type OutboxEvent struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	AggregateID   primitive.ObjectID `bson:"aggregate_id"`
+	RoutingKey    string             `bson:"routing_key"`
+	Payload       []byte             `bson:"payload"`
+	Status        OutboxStatus       `bson:"status"`
+	Attempts      int                `bson:"attempts"`
+	LastError     string             `bson:"last_error,omitempty"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at"`
+	CreatedAt     time.Time          `bson:"created_at"`
+	DeliveredAt   *time.Time         `bson:"delivered_at"`
+	FailedAt      *time.Time         `bson:"failed_at,omitempty"`
+}
+
+func NewOutboxEvent(aggregateID primitive.ObjectID, routingKey string, payload []byte) OutboxEvent {
+	now := time.Now()
+	return OutboxEvent{
+		ID:            primitive.NewObjectID(),
+		AggregateID:   aggregateID,
+		RoutingKey:    routingKey,
+		Payload:       payload,
+		Status:        OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
@@ -6,9 +6,49 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// AnonymousUsername replaces the name of users who deleted their account
+const AnonymousUsername = "anonymous"
+
 type UserInfo struct {
 	Username  string `bson:"username" json:"username"`
 	UserMajor string `bson:"usermajor" json:"usermajor"`
+	//SyncedAt is the time of the last applied user event, older events are ignored
+	SyncedAt *time.Time `bson:"synced_at,omitempty" json:"-"`
+}
+
+type PostType string
+
+const (
+	PostTypeLost  PostType = "lost"
+	PostTypeFound PostType = "found"
+)
+
+// Opposite returns the type of posts that can match this one
+func (t PostType) Opposite() PostType {
+	if t == PostTypeLost {
+		return PostTypeFound
+	}
+	return PostTypeLost
+}
+
+type PostStatus string
+
+const (
+	PostStatusOpen              PostStatus = "open"
+	PostStatusClaimPending      PostStatus = "claim_pending"
+	PostStatusHandoverRequested PostStatus = "handover_requested"
+	PostStatusReturned          PostStatus = "returned"
+	PostStatusExpired           PostStatus = "expired"
+	PostStatusArchived          PostStatus = "archived"
+)
+
+func (s PostStatus) IsValid() bool {
+	switch s {
+	case PostStatusOpen, PostStatusClaimPending, PostStatusHandoverRequested,
+		PostStatusReturned, PostStatusExpired, PostStatusArchived:
+		return true
+	}
+	return false
 }
 
 type Post struct {
@@ -16,9 +56,15 @@ type Post struct {
 	UserID          int64              `bson:"user_id" json:"user_id"`
 	ReturnedTo      int64              `bson:"returned_to" json:"returned_to"`
 	IsReturned      bool               `bson:"is_returned" json:"is_returned"`
+	Type            PostType           `bson:"type" json:"type"`
+	Status          PostStatus         `bson:"status" json:"status"`
 	Title           string             `bson:"title" json:"title"`
 	ImageURL        string             `bson:"image_url" json:"image_url"`
 	ImageKey        string             `bson:"image_key"`
+	Images          []PostImage        `bson:"images" json:"images"`
+	CoverImageID    string             `bson:"cover_image_id" json:"cover_image_id"`
+	MediumImageURL  string             `bson:"medium_image_url" json:"medium_image_url"`
+	SmallImageURL   string             `bson:"small_image_url" json:"small_image_url"`
 	ConfirmationKey string             `bson:"confirmation_key" json:"-"`
 	Place           string             `bson:"place" json:"place"`
 	Description     string             `bson:"description" json:"description,omitempty"`
@@ -29,10 +75,82 @@ type Post struct {
 	DeletedAt       *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at"`
 }
 
+type PostImage struct {
+	ID        string `bson:"id" json:"id"`
+	URL       string `bson:"url" json:"url"`
+	MediumURL string `bson:"medium_url" json:"medium_url"`
+	SmallURL  string `bson:"small_url" json:"small_url"`
+	Key       string `bson:"key" json:"-"`
+	MediumKey string `bson:"medium_key" json:"-"`
+	SmallKey  string `bson:"small_key" json:"-"`
+	Order     int    `bson:"order" json:"order"`
+}
+
+// Keys lists the storage keys of the image, thumbnails included
+func (i PostImage) Keys() []string {
+	keys := make([]string, 0, 3)
+	for _, key := range []string{i.Key, i.MediumKey, i.SmallKey} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ArrangeImages renumbers the images in their current order and keeps ImageURL, ImageKey and
+// the thumbnail urls pointing at the cover, falling back to the first image when the cover is gone
+func (p *Post) ArrangeImages() {
+
+	for i := range p.Images {
+		p.Images[i].Order = i
+	}
+
+	cover, ok := p.FindImage(p.CoverImageID)
+	if !ok {
+		if len(p.Images) == 0 {
+			p.CoverImageID, p.ImageURL, p.ImageKey = "", "", ""
+			p.MediumImageURL, p.SmallImageURL = "", ""
+			return
+		}
+		cover = p.Images[0]
+	}
+
+	p.CoverImageID = cover.ID
+	p.ImageURL = cover.URL
+	p.ImageKey = cover.Key
+	p.MediumImageURL = cover.MediumURL
+	p.SmallImageURL = cover.SmallURL
+}
+
+func (p Post) FindImage(imageID string) (PostImage, bool) {
+	for _, image := range p.Images {
+		if image.ID == imageID {
+			return image, true
+		}
+	}
+	return PostImage{}, false
+}
+
+type PostMatch struct {
+	Post  Post    `json:"post"`
+	Score float64 `json:"score"`
+}
+
 type Characteristic struct {
 	Title string `bson:"title" json:"title"`
 }
 
+// CurrentStatus derives the lifecycle status of posts stored before it was tracked
+func (p Post) CurrentStatus() PostStatus {
+	if p.Status != "" {
+		return p.Status
+	}
+	if p.IsReturned {
+		return PostStatusReturned
+	}
+	return PostStatusOpen
+}
+
 func (p *Post) SetPostID(postID primitive.ObjectID) {
 	p.ID = postID
 }
@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
 // versions:
 // - protoc-gen-go-grpc v1.2.0
-// - protoc             v3.21.4
+// - protoc             (unknown)
 // source: post.proto
 
 package __
@@ -23,6 +23,15 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type PostServiceClient interface {
 	Fetch(ctx context.Context, in *PostIDs, opts ...grpc.CallOption) (*Posts, error)
+	Get(ctx context.Context, in *GetPostRequest, opts ...grpc.CallOption) (*Post, error)
+	List(ctx context.Context, in *ListPostsRequest, opts ...grpc.CallOption) (*PaginatedPosts, error)
+	Search(ctx context.Context, in *SearchPostsRequest, opts ...grpc.CallOption) (*PaginatedPosts, error)
+	Create(ctx context.Context, in *CreatePostRequest, opts ...grpc.CallOption) (*Post, error)
+	Update(ctx context.Context, in *UpdatePostRequest, opts ...grpc.CallOption) (*Post, error)
+	Delete(ctx context.Context, in *DeletePostRequest, opts ...grpc.CallOption) (*DeletePostResponse, error)
+	RequestValidateOwner(ctx context.Context, in *RequestValidateOwnerRequest, opts ...grpc.CallOption) (*RequestValidateOwnerResponse, error)
+	ValidateOwner(ctx context.Context, in *ValidateOwnerRequest, opts ...grpc.CallOption) (*ValidateOwnerResponse, error)
+	WatchPosts(ctx context.Context, in *WatchPostsRequest, opts ...grpc.CallOption) (PostService_WatchPostsClient, error)
 }
 
 type postServiceClient struct {
@@ -42,11 +51,124 @@ func (c *postServiceClient) Fetch(ctx context.Context, in *PostIDs, opts ...grpc
 	return out, nil
 }
 
+func (c *postServiceClient) Get(ctx context.Context, in *GetPostRequest, opts ...grpc.CallOption) (*Post, error) {
+	out := new(Post)
+	err := c.cc.Invoke(ctx, "/model.PostService/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) List(ctx context.Context, in *ListPostsRequest, opts ...grpc.CallOption) (*PaginatedPosts, error) {
+	out := new(PaginatedPosts)
+	err := c.cc.Invoke(ctx, "/model.PostService/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) Search(ctx context.Context, in *SearchPostsRequest, opts ...grpc.CallOption) (*PaginatedPosts, error) {
+	out := new(PaginatedPosts)
+	err := c.cc.Invoke(ctx, "/model.PostService/Search", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) Create(ctx context.Context, in *CreatePostRequest, opts ...grpc.CallOption) (*Post, error) {
+	out := new(Post)
+	err := c.cc.Invoke(ctx, "/model.PostService/Create", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) Update(ctx context.Context, in *UpdatePostRequest, opts ...grpc.CallOption) (*Post, error) {
+	out := new(Post)
+	err := c.cc.Invoke(ctx, "/model.PostService/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) Delete(ctx context.Context, in *DeletePostRequest, opts ...grpc.CallOption) (*DeletePostResponse, error) {
+	out := new(DeletePostResponse)
+	err := c.cc.Invoke(ctx, "/model.PostService/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) RequestValidateOwner(ctx context.Context, in *RequestValidateOwnerRequest, opts ...grpc.CallOption) (*RequestValidateOwnerResponse, error) {
+	out := new(RequestValidateOwnerResponse)
+	err := c.cc.Invoke(ctx, "/model.PostService/RequestValidateOwner", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) ValidateOwner(ctx context.Context, in *ValidateOwnerRequest, opts ...grpc.CallOption) (*ValidateOwnerResponse, error) {
+	out := new(ValidateOwnerResponse)
+	err := c.cc.Invoke(ctx, "/model.PostService/ValidateOwner", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) WatchPosts(ctx context.Context, in *WatchPostsRequest, opts ...grpc.CallOption) (PostService_WatchPostsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PostService_ServiceDesc.Streams[0], "/model.PostService/WatchPosts", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &postServiceWatchPostsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PostService_WatchPostsClient interface {
+	Recv() (*PostChange, error)
+	grpc.ClientStream
+}
+
+type postServiceWatchPostsClient struct {
+	grpc.ClientStream
+}
+
+func (x *postServiceWatchPostsClient) Recv() (*PostChange, error) {
+	m := new(PostChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // PostServiceServer is the server API for PostService service.
 // All implementations must embed UnimplementedPostServiceServer
 // for forward compatibility
 type PostServiceServer interface {
 	Fetch(context.Context, *PostIDs) (*Posts, error)
+	Get(context.Context, *GetPostRequest) (*Post, error)
+	List(context.Context, *ListPostsRequest) (*PaginatedPosts, error)
+	Search(context.Context, *SearchPostsRequest) (*PaginatedPosts, error)
+	Create(context.Context, *CreatePostRequest) (*Post, error)
+	Update(context.Context, *UpdatePostRequest) (*Post, error)
+	Delete(context.Context, *DeletePostRequest) (*DeletePostResponse, error)
+	RequestValidateOwner(context.Context, *RequestValidateOwnerRequest) (*RequestValidateOwnerResponse, error)
+	ValidateOwner(context.Context, *ValidateOwnerRequest) (*ValidateOwnerResponse, error)
+	WatchPosts(*WatchPostsRequest, PostService_WatchPostsServer) error
 	mustEmbedUnimplementedPostServiceServer()
 }
 
@@ -57,6 +179,33 @@ type UnimplementedPostServiceServer struct {
 func (UnimplementedPostServiceServer) Fetch(context.Context, *PostIDs) (*Posts, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Fetch not implemented")
 }
+func (UnimplementedPostServiceServer) Get(context.Context, *GetPostRequest) (*Post, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedPostServiceServer) List(context.Context, *ListPostsRequest) (*PaginatedPosts, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedPostServiceServer) Search(context.Context, *SearchPostsRequest) (*PaginatedPosts, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedPostServiceServer) Create(context.Context, *CreatePostRequest) (*Post, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedPostServiceServer) Update(context.Context, *UpdatePostRequest) (*Post, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedPostServiceServer) Delete(context.Context, *DeletePostRequest) (*DeletePostResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedPostServiceServer) RequestValidateOwner(context.Context, *RequestValidateOwnerRequest) (*RequestValidateOwnerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestValidateOwner not implemented")
+}
+func (UnimplementedPostServiceServer) ValidateOwner(context.Context, *ValidateOwnerRequest) (*ValidateOwnerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateOwner not implemented")
+}
+func (UnimplementedPostServiceServer) WatchPosts(*WatchPostsRequest, PostService_WatchPostsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPosts not implemented")
+}
 func (UnimplementedPostServiceServer) mustEmbedUnimplementedPostServiceServer() {}
 
 // UnsafePostServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +237,171 @@ func _PostService_Fetch_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PostService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/model.PostService/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).Get(ctx, req.(*GetPostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPostsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/model.PostService/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).List(ctx, req.(*ListPostsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchPostsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/model.PostService/Search",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).Search(ctx, req.(*SearchPostsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/model.PostService/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).Create(ctx, req.(*CreatePostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/model.PostService/Update",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).Update(ctx, req.(*UpdatePostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/model.PostService/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).Delete(ctx, req.(*DeletePostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_RequestValidateOwner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestValidateOwnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).RequestValidateOwner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/model.PostService/RequestValidateOwner",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).RequestValidateOwner(ctx, req.(*RequestValidateOwnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_ValidateOwner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateOwnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).ValidateOwner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/model.PostService/ValidateOwner",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).ValidateOwner(ctx, req.(*ValidateOwnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_WatchPosts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPostsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PostServiceServer).WatchPosts(m, &postServiceWatchPostsServer{stream})
+}
+
+type PostService_WatchPostsServer interface {
+	Send(*PostChange) error
+	grpc.ServerStream
+}
+
+type postServiceWatchPostsServer struct {
+	grpc.ServerStream
+}
+
+func (x *postServiceWatchPostsServer) Send(m *PostChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // PostService_ServiceDesc is the grpc.ServiceDesc for PostService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,7 +413,45 @@ var PostService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Fetch",
 			Handler:    _PostService_Fetch_Handler,
 		},
+		{
+			MethodName: "Get",
+			Handler:    _PostService_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _PostService_List_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _PostService_Search_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _PostService_Create_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _PostService_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _PostService_Delete_Handler,
+		},
+		{
+			MethodName: "RequestValidateOwner",
+			Handler:    _PostService_RequestValidateOwner_Handler,
+		},
+		{
+			MethodName: "ValidateOwner",
+			Handler:    _PostService_ValidateOwner_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPosts",
+			Handler:       _PostService_WatchPosts_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "post.proto",
 }
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/models"
+)
+
+type AuditRepository struct {
+	Collection *mongo.Collection
+}
+
+func (a AuditRepository) Record(ctx context.Context, audit models.PostAudit) error {
+	_, err := a.Collection.InsertOne(ctx, audit)
+	return err
+}
+
+func (a AuditRepository) FetchByPost(ctx context.Context, postID string, limit int64, skip int64) ([]models.PostAudit, error) {
+
+	//Convert PostID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find()
+	opts.SetLimit(limit)
+	opts.SetSkip(skip)
+	opts.SetSort(bson.D{{"created_at", -1}})
+
+	cursor, err := a.Collection.Find(ctx, bson.D{{"post_id", objectID}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	audits := make([]models.PostAudit, 0)
+	if err = cursor.All(ctx, &audits); err != nil {
+		return nil, err
+	}
+
+	return audits, nil
+}
+
+func NewAuditRepository(collection *mongo.Collection) contracts.AuditRepositoryContract {
+	return &AuditRepository{Collection: collection}
+}
@@ -2,78 +2,79 @@ package repositories
 
 import (
 	"bytes"
+	"context"
 	"errors"
-	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"golang.org/x/exp/slices"
 	"golek_posts_service/pkg/contracts"
-	"golek_posts_service/pkg/http/responses"
 	"io/ioutil"
 	"log"
 	"mime/multipart"
 	"net/http"
-	"sync"
+	"net/url"
 	"time"
 )
 
 type S3BucketService struct {
+	contracts.UploadValidatorContract
 	maxPartSize     int64
 	maxRetries      int
 	accessKeyID     string
 	secretAccessKey string
 	bucketRegion    string
 	bucketName      string
-	allowedMimeType []string
-	session         *session.Session
-	prefix          *string
+	client          *s3.S3
 }
 
-func NewS3Repository(accessKeyId string, secretAccessKey string, bucketName string, bucketRegion string, allowedMimeType []string, maxPartSize int64, maxRetries int) contracts.StorageRepository {
+// NewS3Repository uploads in parts of maxPartSize, the upload limits are up to the validator
+func NewS3Repository(accessKeyId string, secretAccessKey string, bucketName string, bucketRegion string, validator contracts.UploadValidatorContract, maxPartSize int64, maxRetries int) contracts.StorageRepository {
+	if maxRetries <= 0 {
+		maxRetries = 1
+	}
+	if maxPartSize <= 0 {
+		maxPartSize = 5 * 1024 * 1024
+	}
 	return &S3BucketService{
-		maxPartSize:     maxPartSize,
-		maxRetries:      maxRetries,
-		accessKeyID:     accessKeyId,
-		secretAccessKey: secretAccessKey,
-		bucketRegion:    bucketRegion,
-		bucketName:      bucketName,
-		allowedMimeType: allowedMimeType,
+		UploadValidatorContract: validator,
+		maxPartSize:             maxPartSize,
+		maxRetries:              maxRetries,
+		accessKeyID:             accessKeyId,
+		secretAccessKey:         secretAccessKey,
+		bucketRegion:            bucketRegion,
+		bucketName:              bucketName,
 	}
 }
 
-func (s S3BucketService) GetClient() (*s3.S3, error) {
-
-	var client *s3.S3
+func (s *S3BucketService) Connect() error {
 
 	credential := credentials.NewStaticCredentials(s.accessKeyID, s.secretAccessKey, "")
 	_, err := credential.Get()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	cfg := aws.NewConfig().WithRegion(s.bucketRegion).WithCredentials(credential)
 	newSession, err := session.NewSession(cfg)
 	if err != nil {
-		return nil, err
-	}
-
-	if client == nil {
-		return s3.New(newSession, cfg), nil
+		return err
 	}
 
-	return client, nil
+	s.client = s3.New(newSession, cfg)
+	return nil
+}
 
+// Close has nothing to release, the sdk client keeps no open connections of its own
+func (s *S3BucketService) Close() error {
+	return nil
 }
 
 // ReadFileBytes Read file bytes From multipart request
-func (s S3BucketService) ReadFileBytes(file *multipart.FileHeader) ([]byte, error) {
+func (s *S3BucketService) ReadFileBytes(file *multipart.FileHeader) ([]byte, error) {
 	//Get raw file bytes
-	openedFile, _ := file.Open()
-	filesBytes, err := ioutil.ReadAll(openedFile)
-
+	openedFile, err := file.Open()
 	if err != nil {
 		return nil, err
 	}
@@ -82,467 +83,256 @@ func (s S3BucketService) ReadFileBytes(file *multipart.FileHeader) ([]byte, erro
 	defer func(openedFile multipart.File) {
 		err := openedFile.Close()
 		if err != nil {
-			log.Fatalf("Failed closing file, %v", err.Error())
+			log.Printf("Failed closing file, %v", err.Error())
 		}
 	}(openedFile)
 
-	return filesBytes, nil
+	return ioutil.ReadAll(openedFile)
 }
 
-//Files must be video type
-func (s S3BucketService) validateFilesType(files []*multipart.FileHeader) (bool, error) {
-
-	for _, file := range files {
-		size := file.Size
-		contentType := file.Header.Get("Content-Type")
-
-		if size > s.maxPartSize {
-			return false, errors.New(fmt.Sprintf("File too large, maximum file size is %v MB", s.maxPartSize/(1024*1024)))
-		}
+func (s *S3BucketService) UploadFile(ctx context.Context, file *multipart.FileHeader, name string) (contracts.StoredObject, error) {
 
-		if !slices.Contains(s.allowedMimeType, contentType) {
-			return false, errors.New(fmt.Sprintf("File type %v is not supported, given type %v", file.Filename, contentType))
-		}
+	fileBytes, err := s.ReadFileBytes(file)
+	if err != nil {
+		return contracts.StoredObject{}, err
 	}
 
-	return true, nil
+	return s.Upload(ctx, fileBytes, name)
 }
 
-func (s S3BucketService) validateFileType(file *multipart.FileHeader) (bool, error) {
+func (s *S3BucketService) Upload(ctx context.Context, data []byte, name string) (contracts.StoredObject, error) {
 
-	size := file.Size
-	//contentType := file.Header.Get("Content-Type")
-
-	fileBuff := make([]byte, 512)
-	fileOpen, _ := file.Open()
-	if _, err := fileOpen.Read(fileBuff); err != nil {
-		return false, err
-	}
-
-	contentType := http.DetectContentType(fileBuff)
-
-	if size > s.maxPartSize {
-		return false, errors.New(fmt.Sprintf("File too large, maximum file size is %v MB", s.maxPartSize/(1024*1024)))
+	//1. Validate File
+	contentType, err := s.Validate(data)
+	if err != nil {
+		return contracts.StoredObject{}, err
 	}
 
-	if !slices.Contains(s.allowedMimeType, contentType) {
-		return false, errors.New(fmt.Sprintf("File type %v is not supported, given type %v", file.Filename, contentType))
+	if s.client == nil {
+		return contracts.StoredObject{}, errors.New("s3 client is not connected")
 	}
 
-	return true, nil
-}
+	key := s.ObjectName(name, contentType)
 
-//Construct AWS CompleteMultipartUpload Object
-func (s S3BucketService) completeMultipartUpload(S3 *s3.S3, resp *s3.CreateMultipartUploadOutput, completedParts []*s3.CompletedPart) (*s3.CompleteMultipartUploadOutput, error) {
-	completeInput := &s3.CompleteMultipartUploadInput{
-		Bucket:   resp.Bucket,
-		Key:      resp.Key,
-		UploadId: resp.UploadId,
-		MultipartUpload: &s3.CompletedMultipartUpload{
-			Parts: completedParts,
-		},
+	//2. Prepare s3 multipart upload
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucketName),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
 	}
 
-	return S3.CompleteMultipartUpload(completeInput)
-}
-
-//Construct AWS CompletedPart
-func (s S3BucketService) uploadPart(S3 *s3.S3, resp *s3.CreateMultipartUploadOutput, filesBytes []byte, partNumber int) (*s3.CompletedPart, error) {
+	//3. Create s3 multipart upload
+	createdMultipartOutput, err := s.client.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return contracts.StoredObject{}, err
+	}
 
-	try := 1
+	//4. Upload the parts
+	var current, partLength int64
+	var remaining = int64(len(data))
+	var completedParts []*s3.CompletedPart
 
-	partInput := &s3.UploadPartInput{
-		Body:          bytes.NewReader(filesBytes),
-		Bucket:        resp.Bucket,
-		Key:           resp.Key,
-		PartNumber:    aws.Int64(int64(partNumber)),
-		UploadId:      resp.UploadId,
-		ContentLength: aws.Int64(int64(len(filesBytes))),
-	}
+	partNumber := 1
+	for current = 0; remaining != 0; current += partLength {
+		if remaining < s.maxPartSize {
+			partLength = remaining
+		} else {
+			partLength = s.maxPartSize
+		}
 
-	for try <= s.maxRetries {
-		uploadPartOutput, err := S3.UploadPart(partInput)
+		completedPart, err := s.uploadPart(ctx, createdMultipartOutput, data[current:current+partLength], partNumber)
 		if err != nil {
-			if try == s.maxRetries {
-				if awsErr, ok := err.(awserr.Error); ok {
-					return nil, awsErr
-				}
-				return nil, err
+			if abortErr := s.abortMultiPartUpload(ctx, createdMultipartOutput); abortErr != nil {
+				log.Println(abortErr.Error())
 			}
-			log.Printf("Retrying to upload part #%v\n", partNumber)
-			try++
-		} else {
-			//fmt.Printf("UploadFiles part #%v\n", partNumber)
-			completed := &s3.CompletedPart{
-				ETag:       uploadPartOutput.ETag,
-				PartNumber: aws.Int64(int64(partNumber)),
-			}
-
-			return completed, nil
+			return contracts.StoredObject{}, err
 		}
-	}
 
-	return nil, nil
-}
+		remaining -= partLength
+		partNumber++
+		completedParts = append(completedParts, completedPart)
+	}
 
-//Abort multipart If one of the upload part process failed
-func (s S3BucketService) abortMultiPartUpload(S3 *s3.S3, resp *s3.CreateMultipartUploadOutput) error {
-	log.Println("Aborting multipart upload for UploadId#" + *resp.UploadId)
-	abortInput := &s3.AbortMultipartUploadInput{
-		Bucket:   resp.Bucket,
-		Key:      resp.Key,
-		UploadId: resp.UploadId,
+	//5. Complete the upload
+	if _, err := s.completeMultipartUpload(ctx, createdMultipartOutput, completedParts); err != nil {
+		return contracts.StoredObject{}, err
 	}
-	_, err := S3.AbortMultipartUpload(abortInput)
-	return err
+
+	return contracts.StoredObject{Key: key, URL: s.URL(key), ContentType: contentType}, nil
 }
 
-func (s S3BucketService) UploadFile(file *multipart.FileHeader, prefix string) (responses.S3Response, error) {
+func (s *S3BucketService) Delete(ctx context.Context, key string) error {
 
-	//1. Validate File type
-	isValid, err := s.validateFileType(file)
-	if !isValid {
-		return responses.S3Response{}, err
+	if s.client == nil {
+		return errors.New("s3 client is not connected")
 	}
 
-	//2. Open AWS S3 Session
-	s3Client, _ := s.GetClient()
-
-	//3. Set Time Format
-	timeNow := time.Now()
-	timeNowRFC3339 := timeNow.Format(time.RFC3339)
-
-	//4. Loop over form input and process each part
-	var wg sync.WaitGroup
-	finalResultChannel := make(chan responses.S3Response, 10)
-	pathNumber := 0
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	})
 
-	wg.Add(1)
+	return err
+}
 
-	//5. Get filePart bytes
-	go func(wg *sync.WaitGroup, filePart *multipart.FileHeader, pathNumber int, prefix string) {
+func (s *S3BucketService) PresignUpload(ctx context.Context, key string, contentType string, ttl time.Duration) (contracts.PresignedUpload, error) {
 
-		defer wg.Done()
+	if s.client == nil {
+		return contracts.PresignedUpload{}, errors.New("s3 client is not connected")
+	}
 
-		result := responses.S3Response{}
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:      aws.String(s.bucketName),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	})
+	req.SetContext(ctx)
 
-		fileBytes, err := s.ReadFileBytes(filePart)
-		if err != nil {
-			log.Println(err.Error())
-			result.Success = false
-			result.Filename = filePart.Filename
-			result.Message = err.Error()
-			result.Order = pathNumber
-			result.Key = ""
-			finalResultChannel <- result
-			return
-		}
+	signedURL, err := req.Presign(ttl)
+	if err != nil {
+		return contracts.PresignedUpload{}, err
+	}
 
-		//6. Filename format
-		path := prefix + timeNowRFC3339 + "-" + filePart.Filename
+	return contracts.PresignedUpload{
+		Key:       key,
+		URL:       signedURL,
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
 
-		//7. Get filePart type /mime type
-		fileType := http.DetectContentType(fileBytes)
+func (s *S3BucketService) Stat(ctx context.Context, key string) (contracts.ObjectInfo, error) {
 
-		//8. Prepare s3 multipart upload
-		input := &s3.CreateMultipartUploadInput{
-			Bucket:      aws.String(s.bucketName),
-			Key:         aws.String(path),
-			ContentType: aws.String(fileType),
-		}
+	if s.client == nil {
+		return contracts.ObjectInfo{}, errors.New("s3 client is not connected")
+	}
 
-		//9. Create s3 multipart upload
-		createdMultipartOutput, err := s3Client.CreateMultipartUpload(input)
-		if err != nil {
-			log.Println(err.Error())
-			result.Success = false
-			result.Filename = filePart.Filename
-			result.Order = pathNumber
-			result.Message = err.Error()
-			result.Key = ""
-			finalResultChannel <- result
-			return
+	head, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.RequestFailure); ok && awsErr.StatusCode() == http.StatusNotFound {
+			return contracts.ObjectInfo{}, contracts.ErrUploadNotFound
 		}
+		return contracts.ObjectInfo{}, err
+	}
 
-		log.Println("Created multipart upload request")
-
-		//10. UploadFiles Multipart
-		var current, partLength int64
-		var remaining = filePart.Size
-		var completedParts []*s3.CompletedPart
-
-		partNumber := 1
-		for current = 0; remaining != 0; current += partLength {
-			if remaining < s.maxPartSize {
-				partLength = remaining
-			} else {
-				partLength = s.maxPartSize
-			}
-
-			//UploadFiles
-			completedPart, err := s.uploadPart(s3Client, createdMultipartOutput, fileBytes[current:current+partLength], partNumber)
-
-			if err != nil {
-				log.Println(err.Error())
-
-				err := s.abortMultiPartUpload(s3Client, createdMultipartOutput)
-				if err != nil {
-					log.Println(err.Error())
-					result.Success = false
-					result.Filename = filePart.Filename
-					result.Order = pathNumber
-					result.Message = err.Error()
-					result.Key = ""
-					finalResultChannel <- result
-
-					return
-				}
-
-				result.Success = false
-				result.Filename = filePart.Filename
-				result.Order = pathNumber
-				result.Message = err.Error()
-				result.Key = ""
-				finalResultChannel <- result
-
-				return
-			}
-
-			remaining -= partLength
-			partNumber++
-			completedParts = append(completedParts, completedPart)
-
-		}
+	return contracts.ObjectInfo{
+		Key:         key,
+		Size:        aws.Int64Value(head.ContentLength),
+		ContentType: aws.StringValue(head.ContentType),
+	}, nil
+}
 
-		log.Printf("Uploading file: %s\n", filePart.Filename)
+func (s *S3BucketService) Read(ctx context.Context, key string) ([]byte, error) {
 
-		completeResponse, err := s.completeMultipartUpload(s3Client, createdMultipartOutput, completedParts)
+	if s.client == nil {
+		return nil, errors.New("s3 client is not connected")
+	}
 
-		if err != nil {
-			log.Println(err.Error())
-			result.Success = false
-			result.Filename = filePart.Filename
-			result.Order = pathNumber
-			result.Message = err.Error()
-			result.Key = ""
-			finalResultChannel <- result
-			return
+	object, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, contracts.ErrUploadNotFound
 		}
-
-		log.Printf("File successfully uploaded : %s\n", filePart.Filename)
-
-		//Array of uploaded part's url location
-		result.Success = true
-		result.Filename = filePart.Filename
-		result.Filepath = *completeResponse.Location
-		result.Order = pathNumber
-		result.Key = *completeResponse.Key
-		result.Message = fmt.Sprintf("File %v successfully uploaded", filePart.Filename)
-		finalResultChannel <- result
-
-	}(&wg, file, pathNumber, prefix)
-
-	pathNumber++
-
-	wg.Wait()
-
-	//Close Channel
-	close(finalResultChannel)
-
-	//Loop Over Channel
-
-	return <-finalResultChannel, nil
-}
-
-func (s S3BucketService) UploadFiles(files []*multipart.FileHeader, prefix string) ([]responses.S3Response, error) {
-
-	//1. Validate Inputs
-	valid, err := s.validateFilesType(files)
-	if !valid {
 		return nil, err
 	}
+	defer object.Body.Close()
 
-	//2. Open AWS S3 Session
-	s3Client, _ := s.GetClient()
-
-	//3.
-	now := time.Now()
-	nowRFC3339 := now.Format(time.RFC3339)
-
-	//4. Loop over form input and process each part
-	var wg sync.WaitGroup
-	finalResultChannel := make(chan responses.S3Response, 10)
-	pathNumber := 0
-
-	for _, filePart := range files {
-
-		wg.Add(1)
-
-		//5. Get filePart bytes
-		go func(wg *sync.WaitGroup, filePart *multipart.FileHeader, pathNumber int, prefix string) {
-
-			defer wg.Done()
-
-			result := responses.S3Response{}
-
-			filesBytes, err := s.ReadFileBytes(filePart)
-			if err != nil {
-				log.Println(err.Error())
-				result.Success = false
-				result.Filename = filePart.Filename
-				result.Message = err.Error()
-				result.Order = pathNumber
-				result.Key = ""
-				finalResultChannel <- result
-				return
-			}
-
-			//6. Filename format
-			path := prefix + nowRFC3339 + "-" + filePart.Filename
-
-			//7. Get filePart type /mime type
-			fileType := http.DetectContentType(filesBytes)
-
-			//8. Prepare s3 multipart upload
-			input := &s3.CreateMultipartUploadInput{
-				Bucket:      aws.String(s.bucketName),
-				Key:         aws.String(path),
-				ContentType: aws.String(fileType),
-			}
-
-			//9. Create s3 multipart upload
-			createdMultipartOutput, err := s3Client.CreateMultipartUpload(input)
-			if err != nil {
-				log.Println(err.Error())
-				result.Success = false
-				result.Filename = filePart.Filename
-				result.Order = pathNumber
-				result.Message = err.Error()
-				result.Key = ""
-				finalResultChannel <- result
-				return
-			}
-
-			log.Println("Created multipart upload request")
-
-			//10. UploadFiles Multipart
-			var current, partLength int64
-			var remaining = filePart.Size
-			var completedParts []*s3.CompletedPart
-
-			partNumber := 1
-			for current = 0; remaining != 0; current += partLength {
-
-				if remaining < s.maxPartSize {
-					partLength = remaining
-				} else {
-					partLength = s.maxPartSize
-				}
-
-				//UploadFiles Binaries File
-				completedPart, err := s.uploadPart(s3Client, createdMultipartOutput, filesBytes[current:current+partLength], partNumber)
-
-				if err != nil {
-					log.Println(err.Error())
-
-					err := s.abortMultiPartUpload(s3Client, createdMultipartOutput)
-					if err != nil {
-						log.Println(err.Error())
-						result.Success = false
-						result.Filename = filePart.Filename
-						result.Order = pathNumber
-						result.Message = err.Error()
-						result.Key = ""
-						finalResultChannel <- result
-
-						return
-					}
-
-					result.Success = false
-					result.Filename = filePart.Filename
-					result.Order = pathNumber
-					result.Message = err.Error()
-					result.Key = ""
-					finalResultChannel <- result
-
-					return
-				}
-
-				remaining -= partLength
-				partNumber++
-				completedParts = append(completedParts, completedPart)
-
-			}
+	//The object may have been replaced since it was checked, never read more than an upload may hold
+	return s.ReadLimited(object.Body)
+}
 
-			log.Printf("Uploading file: %s\n", filePart.Filename)
+func (s *S3BucketService) List(ctx context.Context, prefix string, fn func(object contracts.ObjectInfo) error) error {
 
-			completeResponse, err := s.completeMultipartUpload(s3Client, createdMultipartOutput, completedParts)
+	if s.client == nil {
+		return errors.New("s3 client is not connected")
+	}
 
-			if err != nil {
-				log.Println(err.Error())
-				result.Success = false
-				result.Filename = filePart.Filename
-				result.Order = pathNumber
-				result.Message = err.Error()
-				result.Key = ""
-				finalResultChannel <- result
-				return
+	var fnErr error
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucketName),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			fnErr = fn(contracts.ObjectInfo{
+				Key:       aws.StringValue(object.Key),
+				Size:      aws.Int64Value(object.Size),
+				UpdatedAt: aws.TimeValue(object.LastModified),
+			})
+			if fnErr != nil {
+				return false
 			}
-
-			log.Printf("File successfully uploaded : %s\n", filePart.Filename)
-
-			//Array of uploaded part's url location
-			result.Success = true
-			result.Filename = filePart.Filename
-			result.Filepath = *completeResponse.Location
-			result.Order = pathNumber
-			result.Key = *completeResponse.Key
-			result.Message = fmt.Sprintf("File %v successfully uploaded", filePart.Filename)
-			finalResultChannel <- result
-
-		}(&wg, filePart, pathNumber, prefix)
-
-		pathNumber++
+		}
+		return true
+	})
+	if fnErr != nil {
+		return fnErr
 	}
 
-	wg.Wait()
-
-	//Close Channel
-	close(finalResultChannel)
+	return err
+}
 
-	//Loop Over Channel
-	var finalResult []responses.S3Response
+func (s *S3BucketService) URL(key string) string {
+	return "https://" + s.bucketName + ".s3." + s.bucketRegion + ".amazonaws.com/" + (&url.URL{Path: key}).EscapedPath()
+}
 
-	for res := range finalResultChannel {
-		finalResult = append(finalResult, res)
+// Construct AWS CompleteMultipartUpload Object
+func (s *S3BucketService) completeMultipartUpload(ctx context.Context, resp *s3.CreateMultipartUploadOutput, completedParts []*s3.CompletedPart) (*s3.CompleteMultipartUploadOutput, error) {
+	completeInput := &s3.CompleteMultipartUploadInput{
+		Bucket:   resp.Bucket,
+		Key:      resp.Key,
+		UploadId: resp.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: completedParts,
+		},
 	}
 
-	return finalResult, nil
-
+	return s.client.CompleteMultipartUploadWithContext(ctx, completeInput)
 }
 
-func (s S3BucketService) DeleteObject(objectKey *string) error {
+// Construct AWS CompletedPart
+func (s *S3BucketService) uploadPart(ctx context.Context, resp *s3.CreateMultipartUploadOutput, filesBytes []byte, partNumber int) (*s3.CompletedPart, error) {
 
-	client, err := s.GetClient()
-	if err != nil {
-		return err
+	partInput := &s3.UploadPartInput{
+		Body:          bytes.NewReader(filesBytes),
+		Bucket:        resp.Bucket,
+		Key:           resp.Key,
+		PartNumber:    aws.Int64(int64(partNumber)),
+		UploadId:      resp.UploadId,
+		ContentLength: aws.Int64(int64(len(filesBytes))),
 	}
 
-	_, err = client.DeleteObject(&s3.DeleteObjectInput{
-		Bucket: aws.String(s.bucketName),
-		Key:    objectKey,
-	})
-	if err != nil {
-		return err
-	}
+	var err error
+	for try := 1; try <= s.maxRetries; try++ {
+		uploadPartOutput, uploadErr := s.client.UploadPartWithContext(ctx, partInput)
+		if uploadErr == nil {
+			return &s3.CompletedPart{
+				ETag:       uploadPartOutput.ETag,
+				PartNumber: aws.Int64(int64(partNumber)),
+			}, nil
+		}
 
-	err = client.WaitUntilObjectNotExists(&s3.HeadObjectInput{
-		Bucket: aws.String(s.bucketName),
-		Key:    objectKey,
-	})
-	if err != nil {
-		return err
+		err = uploadErr
+		log.Printf("Retrying to upload part #%v\n", partNumber)
 	}
 
-	return nil
+	return nil, err
+}
+
+// Abort multipart If one of the upload part process failed
+func (s *S3BucketService) abortMultiPartUpload(ctx context.Context, resp *s3.CreateMultipartUploadOutput) error {
+	log.Println("Aborting multipart upload for UploadId#" + *resp.UploadId)
+	abortInput := &s3.AbortMultipartUploadInput{
+		Bucket:   resp.Bucket,
+		Key:      resp.Key,
+		UploadId: resp.UploadId,
+	}
+	_, err := s.client.AbortMultipartUploadWithContext(ctx, abortInput)
+	return err
 }
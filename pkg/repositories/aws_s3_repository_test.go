@@ -2,7 +2,6 @@ package repositories
 
 import (
 	"bytes"
-	"golek_posts_service/cmd/msg_broker"
 	"golek_posts_service/pkg/contracts"
 	"golek_posts_service/pkg/database"
 	"golek_posts_service/pkg/database/migration"
@@ -16,6 +15,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -39,12 +39,15 @@ func init() {
 	engine = gin.Default()
 
 	db := database.Database{
-		DbName:       os.Getenv("DB_NAME"),
-		DbCollection: os.Getenv("DB_COLLECTION"),
-		DbHost:       os.Getenv("DB_HOST"),
-		DbPort:       os.Getenv("DB_PORT"),
-		DbUsername:   os.Getenv("DB_USERNAME"),
-		DBPassword:   os.Getenv("DB_PASSWORD"),
+		DbName:             os.Getenv("DB_NAME"),
+		DbCollection:       os.Getenv("DB_COLLECTION"),
+		DbClaimCollection:  "claims",
+		DbAuditCollection:  "post_audits",
+		DbOutboxCollection: "post_outbox",
+		DbHost:             os.Getenv("DB_HOST"),
+		DbPort:             os.Getenv("DB_PORT"),
+		DbUsername:         os.Getenv("DB_USERNAME"),
+		DBPassword:         os.Getenv("DB_PASSWORD"),
 	}
 	db.Prepare()
 
@@ -53,33 +56,41 @@ func init() {
 	m.MigrateSettings()
 
 	//Initialize Repositories
-	postRepository := NewPostRepository(db.GetConnection(), db.GetCollection())
-	qrcodeRepository := NewQRCodeRepository()
+	postRepository := NewPostRepository(db.GetConnection(), db.GetCollection(), db.GetOutboxCollection(), db.GetAuditCollection())
+	claimRepository := NewClaimRepository(db.GetClaimCollection())
+	auditRepository := NewAuditRepository(db.GetAuditCollection())
+	outboxRepository := NewOutboxRepository(db.GetOutboxCollection())
+	imageProcessor := services.NewImageProcessor(0, 0, 0, 0, 0)
+	qrcodeRepository := NewQRCodeRepository(256, "M")
 	s3Repo = NewS3Repository(
 		os.Getenv("AWS_ACCESS_KEY_ID"),
 		os.Getenv("AWS_SECRET_ACCESS_KEY"),
 		os.Getenv("AWS_BUCKET_NAME"),
 		os.Getenv("AWS_BUCKET_REGION"),
-		[]string{"image/jpeg", "image/png", "image/jpg"},
-		int64(5*1024*1024), //MAX Filesize 5mb
+		NewUploadValidator([]string{"image/jpeg", "image/png", "image/jpg"}, int64(5*1024*1024), 0), //MAX Filesize 5mb
+		0,
 		3,
 	)
-
-	//Establish Message Broker Connection
-	amqpConn := msg_broker.New(
-		os.Getenv("RABBITMQ_USER"),
-		os.Getenv("RABBITMQ_PASSWORD"),
-		os.Getenv("RABBITMQ_HOST"),
-		os.Getenv("RABBITMQ_PORT"),
-	)
-	mqPublisherService := msg_broker.NewMQPublisher(amqpConn)
-	mqPublisherService.Setup()
+	if err := s3Repo.Connect(); err != nil {
+		panic(err)
+	}
 
 	//Initialize Services
-	postService := services.NewPostService(&postRepository, &qrcodeRepository, &s3Repo, &mqPublisherService)
+	validationTokenService, err := services.NewValidationTokenService(os.Getenv("VALIDATION_SECRET"), 15*time.Minute)
+	if err != nil {
+		panic(err)
+	}
+
+	matchingService := services.NewMatchingService(&postRepository, 200, 0.2)
+
+	postService := services.NewPostService(&postRepository, &qrcodeRepository, &s3Repo, &outboxRepository, &validationTokenService, &matchingService, &claimRepository, &auditRepository, &imageProcessor, 5)
+
+	claimService := services.NewClaimService(&claimRepository, &postRepository)
+
+	urlResolver := services.NewURLResolver("", "", nil, 0)
 
 	//Initialize Routes
-	controllers.SetupHandler(engine, &postService)
+	controllers.SetupHandler(engine, &postService, &claimService, &urlResolver)
 }
 
 func TestAwsS3StorageRepository(t *testing.T) {
@@ -140,7 +151,7 @@ func TestAwsS3StorageRepository(t *testing.T) {
 				return
 			}
 
-			fileBytes, err := s3Repo.ReadFileBytes(createReq.Image)
+			fileBytes, err := s3Repo.(*S3BucketService).ReadFileBytes(createReq.Image)
 			if err != nil {
 				context.JSON(http.StatusInternalServerError, gin.H{
 					"error": err.Error(),
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/models"
+	"time"
+)
+
+type ClaimRepository struct {
+	Collection *mongo.Collection
+}
+
+func (c ClaimRepository) Fetch(ctx context.Context, filter map[string]any) ([]models.Claim, error) {
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{"created_at", -1}})
+
+	cursor, err := c.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make([]models.Claim, 0)
+	if err = cursor.All(ctx, &claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (c ClaimRepository) FindOne(ctx context.Context, filter map[string]any) (models.Claim, error) {
+
+	var claim models.Claim
+
+	err := c.Collection.FindOne(ctx, filter).Decode(&claim)
+	if err != nil {
+		return claim, err
+	}
+
+	return claim, nil
+}
+
+func (c ClaimRepository) FindById(ctx context.Context, claimID string) (models.Claim, error) {
+
+	//Convert ClaimID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(claimID)
+	if err != nil {
+		return models.Claim{}, err
+	}
+
+	return c.FindOne(ctx, map[string]any{"_id": objectID})
+}
+
+func (c ClaimRepository) Create(ctx context.Context, claim models.Claim) (models.Claim, status.PostOperationStatus, error) {
+
+	_, err := c.Collection.InsertOne(ctx, claim)
+	if err != nil {
+		return models.Claim{}, status.ClaimCreatedStatusFailed, err
+	}
+
+	return claim, status.ClaimCreatedStatusSuccess, nil
+}
+
+// UpdateStatus moves the claim to a new status only if it is still in the expected one
+func (c ClaimRepository) UpdateStatus(ctx context.Context, claimID string, from models.ClaimStatus, to models.ClaimStatus, decidedBy int64) (models.Claim, status.PostOperationStatus, error) {
+
+	//Convert ClaimID to Mongo ObjectID
+	objectID, err := primitive.ObjectIDFromHex(claimID)
+	if err != nil {
+		return models.Claim{}, status.ClaimUpdatedStatusFailed, err
+	}
+
+	timeNow := time.Now()
+
+	filter := bson.D{{"_id", objectID}, {"status", from}}
+	update := bson.D{{"$set", bson.D{
+		{"status", to},
+		{"decided_by", decidedBy},
+		{"decided_at", timeNow},
+		{"updated_at", timeNow},
+	}}}
+
+	var claim models.Claim
+
+	err = c.Collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&claim)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Claim{}, status.ClaimAlreadyDecided, errors.New("claim is no longer " + string(from))
+	}
+	//The partial unique index on approved claims rejects a second approval of the post
+	if mongo.IsDuplicateKeyError(err) {
+		return models.Claim{}, status.ClaimAlreadyApproved, errors.New("another claim is already approved")
+	}
+	if err != nil {
+		return models.Claim{}, status.ClaimUpdatedStatusFailed, err
+	}
+
+	return claim, status.ClaimUpdatedStatusSuccess, nil
+}
+
+func (c ClaimRepository) Delete(ctx context.Context, claimID string) error {
+
+	//Convert ClaimID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(claimID)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Collection.DeleteOne(ctx, bson.D{{"_id", objectID}})
+	return err
+}
+
+func (c ClaimRepository) DeleteByPost(ctx context.Context, postID string) (int64, error) {
+
+	//Convert PostID to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return 0, err
+	}
+
+	deleteResult, err := c.Collection.DeleteMany(ctx, bson.D{{"post_id", objectID}})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleteResult.DeletedCount, nil
+}
+
+func NewClaimRepository(collection *mongo.Collection) contracts.ClaimRepositoryContract {
+	return &ClaimRepository{Collection: collection}
+}
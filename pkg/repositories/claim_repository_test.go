@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/database"
+	"golek_posts_service/pkg/database/migration"
+	"golek_posts_service/pkg/models"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestClaimRepository(t *testing.T) {
+
+	//Load .Env
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		panic(err)
+	}
+
+	db := database.Database{
+		DbName:            os.Getenv("DB_NAME"),
+		DbCollection:      os.Getenv("DB_COLLECTION"),
+		DbClaimCollection: "claims",
+		DbHost:            os.Getenv("DB_HOST"),
+		DbPort:            os.Getenv("DB_PORT"),
+		DbUsername:        os.Getenv("DB_USERNAME"),
+		DBPassword:        os.Getenv("DB_PASSWORD"),
+	}
+	db.Prepare()
+	migration.NewMigration(&db).CreateClaimIndexes()
+	claimRepo := NewClaimRepository(db.GetClaimCollection())
+
+	createClaims := func(postID primitive.ObjectID, count int) []models.Claim {
+		claims := make([]models.Claim, 0, count)
+		for i := 0; i < count; i++ {
+			claim, _, err := claimRepo.Create(context.TODO(), models.Claim{
+				ID:         primitive.NewObjectID(),
+				PostID:     postID,
+				ClaimantID: int64(100 + i),
+				Status:     models.ClaimStatusPending,
+			})
+			assert.NoError(t, err)
+			claims = append(claims, claim)
+		}
+		return claims
+	}
+
+	t.Run("Concurrent Approvals", func(t *testing.T) {
+		postID := primitive.NewObjectID()
+		claims := createClaims(postID, 5)
+
+		var wg sync.WaitGroup
+		results := make([]status.PostOperationStatus, len(claims))
+		for i, claim := range claims {
+			wg.Add(1)
+			go func(i int, claim models.Claim) {
+				defer wg.Done()
+				_, results[i], _ = claimRepo.UpdateStatus(context.TODO(), claim.ID.Hex(), models.ClaimStatusPending, models.ClaimStatusApproved, 7)
+			}(i, claim)
+		}
+		wg.Wait()
+
+		approved := 0
+		for _, result := range results {
+			if result == status.ClaimUpdatedStatusSuccess {
+				approved++
+			} else {
+				assert.Equal(t, status.ClaimAlreadyApproved, result)
+			}
+		}
+		assert.Equal(t, 1, approved)
+
+		_, err := claimRepo.DeleteByPost(context.TODO(), postID.Hex())
+		assert.NoError(t, err)
+	})
+
+	t.Run("Decided Claims Conflict", func(t *testing.T) {
+		postID := primitive.NewObjectID()
+		claim := createClaims(postID, 1)[0]
+
+		rejected, opStatus, err := claimRepo.UpdateStatus(context.TODO(), claim.ID.Hex(), models.ClaimStatusPending, models.ClaimStatusRejected, 7)
+		assert.NoError(t, err)
+		assert.Equal(t, status.ClaimUpdatedStatusSuccess, opStatus)
+		assert.Equal(t, int64(7), rejected.DecidedBy)
+
+		_, opStatus, err = claimRepo.UpdateStatus(context.TODO(), claim.ID.Hex(), models.ClaimStatusPending, models.ClaimStatusRejected, 7)
+		assert.Error(t, err)
+		assert.Equal(t, status.ClaimAlreadyDecided, opStatus)
+
+		_, err = claimRepo.DeleteByPost(context.TODO(), postID.Hex())
+		assert.NoError(t, err)
+	})
+}
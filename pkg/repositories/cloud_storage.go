@@ -3,68 +3,147 @@ package repositories
 import (
 	"cloud.google.com/go/storage"
 	"context"
-	"fmt"
 	"golek_posts_service/pkg/contracts"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
-	"io/ioutil"
 	"mime/multipart"
+	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 type GoogleCloudStorageService struct {
+	contracts.UploadValidatorContract
 	client     *storage.Client
 	keyPath    string
 	bucketName string
 }
 
-func (g *GoogleCloudStorageService) UploadFile(ctx context.Context, file *multipart.FileHeader, name string) (string, error) {
+func (g *GoogleCloudStorageService) UploadFile(ctx context.Context, file *multipart.FileHeader, name string) (contracts.StoredObject, error) {
 
-	bucket := g.client.Bucket(g.bucketName)
-
-	//Open File
-	openedFile, err := file.Open()
+	fileBytes, err := g.ReadUpload(file)
 	if err != nil {
-		return "", err
+		return contracts.StoredObject{}, err
 	}
 
-	defer func(openedFile multipart.File) {
-		err := openedFile.Close()
-		if err != nil {
+	return g.Upload(ctx, fileBytes, name)
+}
 
-		}
-	}(openedFile)
+func (g *GoogleCloudStorageService) Upload(ctx context.Context, data []byte, name string) (contracts.StoredObject, error) {
 
-	//Read File
-	fileBytes, err := ioutil.ReadAll(openedFile)
+	contentType, err := g.Validate(data)
 	if err != nil {
-		return "", err
+		return contracts.StoredObject{}, err
 	}
 
+	key := g.ObjectName(name, contentType)
+	bucket := g.client.Bucket(g.bucketName)
+
 	//Write bytes to Bucket Object
-	w := bucket.Object(name).NewWriter(ctx)
-	_, _ = w.Write(fileBytes)
+	w := bucket.Object(key).NewWriter(ctx)
+	w.ContentType = contentType
+
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return contracts.StoredObject{}, err
+	}
 
 	err = w.Close()
 	if err != nil {
-		return "", err
+		return contracts.StoredObject{}, err
 	}
 
-	parsed, err := url.Parse(w.Attrs().Name)
+	return contracts.StoredObject{Key: key, URL: g.URL(key), ContentType: contentType}, nil
+}
+
+func (g *GoogleCloudStorageService) Delete(ctx context.Context, key string) error {
+
+	err := g.client.Bucket(g.bucketName).Object(key).Delete(ctx)
+	if err != nil && err != storage.ErrObjectNotExist {
+		return err
+	}
+
+	return nil
+}
+
+func (g *GoogleCloudStorageService) PresignUpload(ctx context.Context, key string, contentType string, ttl time.Duration) (contracts.PresignedUpload, error) {
+
+	expiresAt := time.Now().Add(ttl)
+
+	signedURL, err := g.client.Bucket(g.bucketName).SignedURL(key, &storage.SignedURLOptions{
+		Scheme:      storage.SigningSchemeV4,
+		Method:      http.MethodPut,
+		ContentType: contentType,
+		Expires:     expiresAt,
+	})
 	if err != nil {
-		return "", err
+		return contracts.PresignedUpload{}, err
 	}
 
-	return "https://storage.googleapis.com/" + g.bucketName + "/" + parsed.EscapedPath(), nil
+	return contracts.PresignedUpload{
+		Key:       key,
+		URL:       signedURL,
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: expiresAt,
+	}, nil
 }
 
-func (g *GoogleCloudStorageService) DeleteFile(ctx context.Context, fileUrl string) error {
+func (g *GoogleCloudStorageService) Stat(ctx context.Context, key string) (contracts.ObjectInfo, error) {
 
-	bucket := g.client.Bucket(g.bucketName)
-	object := bucket.Object(g.extractObjectName(fileUrl))
-	_ = object.Delete(ctx)
+	attrs, err := g.client.Bucket(g.bucketName).Object(key).Attrs(ctx)
+	if err == storage.ErrObjectNotExist {
+		return contracts.ObjectInfo{}, contracts.ErrUploadNotFound
+	}
+	if err != nil {
+		return contracts.ObjectInfo{}, err
+	}
 
-	return nil
+	return contracts.ObjectInfo{Key: key, Size: attrs.Size, ContentType: attrs.ContentType, UpdatedAt: attrs.Updated}, nil
+}
+
+func (g *GoogleCloudStorageService) Read(ctx context.Context, key string) ([]byte, error) {
+
+	reader, err := g.client.Bucket(g.bucketName).Object(key).NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, contracts.ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	//The object may have been replaced since it was checked, never read more than an upload may hold
+	return g.ReadLimited(reader)
+}
+
+func (g *GoogleCloudStorageService) List(ctx context.Context, prefix string, fn func(object contracts.ObjectInfo) error) error {
+
+	objects := g.client.Bucket(g.bucketName).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := objects.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = fn(contracts.ObjectInfo{
+			Key:         attrs.Name,
+			Size:        attrs.Size,
+			ContentType: attrs.ContentType,
+			UpdatedAt:   attrs.Updated,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (g *GoogleCloudStorageService) URL(key string) string {
+	return "https://storage.googleapis.com/" + g.bucketName + "/" + (&url.URL{Path: key}).EscapedPath()
 }
 
 func (g *GoogleCloudStorageService) Connect() error {
@@ -78,20 +157,29 @@ func (g *GoogleCloudStorageService) Connect() error {
 	return nil
 }
 
-func (g *GoogleCloudStorageService) extractObjectName(urls string) string {
-	uri, err := url.Parse(urls)
+func (g *GoogleCloudStorageService) Close() error {
+	if g.client == nil {
+		return nil
+	}
+	return g.client.Close()
+}
+
+// KeyFromURL recovers the key of images uploaded before keys were stored on posts
+func (g *GoogleCloudStorageService) KeyFromURL(fileUrl string) (string, bool) {
+
+	uri, err := url.Parse(fileUrl)
 	if err != nil {
-		panic(err)
+		return "", false
 	}
 
-	path := uri.Path
-	// remove the prefix "/ayocode1-bucker/"
-	path = path[len(fmt.Sprintf("/%s/", g.bucketName)):]
+	prefix := "/" + g.bucketName + "/"
+	if uri.Host != "storage.googleapis.com" || !strings.HasPrefix(uri.Path, prefix) {
+		return "", false
+	}
 
-	// replace the space with "_"
-	return strings.Replace(path, "%20", " ", -1)
+	return strings.TrimPrefix(uri.Path, prefix), true
 }
 
-func NewGCStorageService(bucketName string, keyPath string) contracts.ICloudStorageRepo {
-	return &GoogleCloudStorageService{client: nil, bucketName: bucketName, keyPath: keyPath}
+func NewGCStorageService(bucketName string, keyPath string, validator contracts.UploadValidatorContract) contracts.StorageRepository {
+	return &GoogleCloudStorageService{UploadValidatorContract: validator, client: nil, bucketName: bucketName, keyPath: keyPath}
 }
@@ -0,0 +1,302 @@
+package repositories
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"golek_posts_service/pkg/contracts"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStorageService keeps uploads on disk. The files are served by a static route
+// mounted at /<bucketName>, so urls look like the ones of the cloud bucket
+type LocalStorageService struct {
+	contracts.UploadValidatorContract
+	rootDir       string
+	publicURL     string
+	bucketName    string
+	signingSecret []byte
+}
+
+func (l *LocalStorageService) UploadFile(ctx context.Context, file *multipart.FileHeader, name string) (contracts.StoredObject, error) {
+
+	fileBytes, err := l.ReadUpload(file)
+	if err != nil {
+		return contracts.StoredObject{}, err
+	}
+
+	return l.Upload(ctx, fileBytes, name)
+}
+
+func (l *LocalStorageService) Upload(ctx context.Context, data []byte, name string) (contracts.StoredObject, error) {
+
+	contentType, err := l.Validate(data)
+	if err != nil {
+		return contracts.StoredObject{}, err
+	}
+
+	key := l.ObjectName(name, contentType)
+	path, err := l.objectPath(key)
+	if err != nil {
+		return contracts.StoredObject{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return contracts.StoredObject{}, err
+	}
+
+	if err := ioutil.WriteFile(path, data, 0o644); err != nil {
+		return contracts.StoredObject{}, err
+	}
+
+	return contracts.StoredObject{Key: key, URL: l.URL(key), ContentType: contentType}, nil
+}
+
+// PresignUpload signs a PUT to the UploadHandler the same way the cloud buckets sign their urls
+func (l *LocalStorageService) PresignUpload(ctx context.Context, key string, contentType string, ttl time.Duration) (contracts.PresignedUpload, error) {
+
+	if _, err := l.objectPath(key); err != nil {
+		return contracts.PresignedUpload{}, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("content_type", contentType)
+	query.Set("signature", l.sign(key, expires, contentType))
+
+	return contracts.PresignedUpload{
+		Key:       key,
+		URL:       l.publicURL + l.UploadRoute() + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(),
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (l *LocalStorageService) Stat(ctx context.Context, key string) (contracts.ObjectInfo, error) {
+
+	path, err := l.objectPath(key)
+	if err != nil {
+		return contracts.ObjectInfo{}, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return contracts.ObjectInfo{}, contracts.ErrUploadNotFound
+	}
+	if err != nil {
+		return contracts.ObjectInfo{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return contracts.ObjectInfo{}, err
+	}
+
+	head := make([]byte, 512)
+	n, _ := file.Read(head)
+
+	return contracts.ObjectInfo{Key: key, Size: info.Size(), ContentType: http.DetectContentType(head[:n]), UpdatedAt: info.ModTime()}, nil
+}
+
+func (l *LocalStorageService) Read(ctx context.Context, key string) ([]byte, error) {
+
+	path, err := l.objectPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, contracts.ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return l.ReadLimited(file)
+}
+
+func (l *LocalStorageService) List(ctx context.Context, prefix string, fn func(object contracts.ObjectInfo) error) error {
+
+	root := filepath.Clean(l.rootDir)
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		return fn(contracts.ObjectInfo{Key: key, Size: info.Size(), UpdatedAt: info.ModTime()})
+	})
+}
+
+func (l *LocalStorageService) Delete(ctx context.Context, key string) error {
+
+	path, err := l.objectPath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (l *LocalStorageService) URL(key string) string {
+	return l.publicURL + "/" + l.bucketName + "/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+func (l *LocalStorageService) Connect() error {
+	return os.MkdirAll(l.rootDir, 0o755)
+}
+
+func (l *LocalStorageService) Close() error {
+	return nil
+}
+
+// Route is the path the static file handler has to be mounted on
+func (l *LocalStorageService) Route() string {
+	return "/" + l.bucketName
+}
+
+// Dir is the directory the static file handler serves
+func (l *LocalStorageService) Dir() string {
+	return l.rootDir
+}
+
+// UploadRoute is the path the UploadHandler has to be mounted on
+func (l *LocalStorageService) UploadRoute() string {
+	return "/_uploads/" + l.bucketName
+}
+
+// UploadHandler accepts the PUT requests signed by PresignUpload
+func (l *LocalStorageService) UploadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		key, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), l.UploadRoute()+"/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		query := r.URL.Query()
+		expires, contentType := query.Get("expires"), query.Get("content_type")
+
+		expiresAt, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil || time.Now().Unix() > expiresAt {
+			http.Error(w, "upload url expired", http.StatusForbidden)
+			return
+		}
+
+		if !hmac.Equal([]byte(query.Get("signature")), []byte(l.sign(key, expires, contentType))) ||
+			r.Header.Get("Content-Type") != contentType {
+			http.Error(w, "upload url signature mismatch", http.StatusForbidden)
+			return
+		}
+
+		if r.ContentLength < 0 {
+			w.WriteHeader(http.StatusLengthRequired)
+			return
+		}
+
+		if err := l.ValidateMeta(contentType, r.ContentLength); err != nil {
+			code := http.StatusUnsupportedMediaType
+			if errors.Is(err, contracts.ErrUploadTooLarge) {
+				code = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		data, err := ioutil.ReadAll(io.LimitReader(r.Body, r.ContentLength))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		path, err := l.objectPath(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := ioutil.WriteFile(path, data, 0o644); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (l *LocalStorageService) sign(key string, expires string, contentType string) string {
+	mac := hmac.New(sha256.New, l.signingSecret)
+	mac.Write([]byte(key + "\n" + expires + "\n" + contentType))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// objectPath resolves a key to its file and refuses anything outside rootDir
+func (l *LocalStorageService) objectPath(key string) (string, error) {
+
+	root := filepath.Clean(l.rootDir)
+	path := filepath.Join(root, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+		return "", errors.New("object " + key + " is outside of the local storage")
+	}
+
+	return path, nil
+}
+
+// NewLocalStorageService fails without a signing secret, anyone could sign upload urls with an empty key
+func NewLocalStorageService(rootDir string, publicURL string, bucketName string, signingSecret string, validator contracts.UploadValidatorContract) (contracts.StorageRepository, error) {
+
+	if signingSecret == "" {
+		return nil, errors.New("local storage requires a signing secret for its upload urls")
+	}
+
+	return &LocalStorageService{
+		UploadValidatorContract: validator,
+		rootDir:                 rootDir,
+		publicURL:               strings.TrimRight(publicURL, "/"),
+		bucketName:              bucketName,
+		signingSecret:           []byte(signingSecret),
+	}, nil
+}
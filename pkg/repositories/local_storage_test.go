@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageBackends(t *testing.T) {
+
+	var encoded bytes.Buffer
+	err := png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	assert.NoError(t, err)
+
+	validator := NewUploadValidator(nil, 0, 0)
+
+	t.Run("Local Storage", func(t *testing.T) {
+		dir := t.TempDir()
+		storage, err := NewLocalStorageService(dir, "http://localhost:8080/", "bucket", "secret", validator)
+		assert.NoError(t, err)
+		assert.NoError(t, storage.Connect())
+
+		stored, err := storage.Upload(context.Background(), encoded.Bytes(), "posts/abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "image/png", stored.ContentType)
+		assert.Equal(t, "http://localhost:8080/bucket/"+stored.Key, stored.URL)
+
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(stored.Key)))
+		assert.NoError(t, err)
+		assert.Equal(t, encoded.Bytes(), content)
+
+		assert.NoError(t, storage.Delete(context.Background(), stored.Key))
+		_, err = os.Stat(filepath.Join(dir, filepath.FromSlash(stored.Key)))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Local Storage Stays Inside Its Directory", func(t *testing.T) {
+		dir := t.TempDir()
+		outside := filepath.Join(filepath.Dir(dir), "outside.png")
+		assert.NoError(t, os.WriteFile(outside, encoded.Bytes(), 0o644))
+		defer os.Remove(outside)
+
+		storage, err := NewLocalStorageService(dir, "http://localhost:8080", "bucket", "secret", validator)
+		assert.NoError(t, err)
+		assert.Error(t, storage.Delete(context.Background(), "../outside.png"))
+
+		_, err = os.Stat(outside)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Local Presigned Upload", func(t *testing.T) {
+		dir := t.TempDir()
+		storage, err := NewLocalStorageService(dir, "http://localhost:8080", "bucket", "secret", validator)
+		assert.NoError(t, err)
+		local := storage.(*LocalStorageService)
+
+		upload, err := storage.PresignUpload(context.Background(), "2026/10/uploads/7-0123456789abcdef.png", "image/png", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodPut, upload.Method)
+
+		put := func(target string, contentType string) int {
+			req := httptest.NewRequest(http.MethodPut, target, bytes.NewReader(encoded.Bytes()))
+			req.Header.Set("Content-Type", contentType)
+			res := httptest.NewRecorder()
+			local.UploadHandler().ServeHTTP(res, req)
+			return res.Code
+		}
+
+		assert.Equal(t, http.StatusForbidden, put(strings.Replace(upload.URL, "7-0123", "8-0123", 1), "image/png"))
+		assert.Equal(t, http.StatusForbidden, put(upload.URL, "image/jpeg"))
+
+		tampered, _ := url.Parse(upload.URL)
+		query := tampered.Query()
+		query.Set("signature", strings.Repeat("0", 64))
+		tampered.RawQuery = query.Encode()
+		assert.Equal(t, http.StatusForbidden, put(tampered.String(), "image/png"))
+
+		expired, err := storage.PresignUpload(context.Background(), upload.Key, "image/png", -time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, put(expired.URL, "image/png"))
+
+		assert.Equal(t, http.StatusOK, put(upload.URL, "image/png"))
+
+		info, err := storage.Stat(context.Background(), upload.Key)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(encoded.Len()), info.Size)
+		assert.Equal(t, "image/png", info.ContentType)
+
+		_, err = storage.Stat(context.Background(), "2026/10/uploads/7-missing.png")
+		assert.ErrorIs(t, err, contracts.ErrUploadNotFound)
+	})
+
+	t.Run("Local Storage Requires A Signing Secret", func(t *testing.T) {
+		_, err := NewLocalStorageService(t.TempDir(), "http://localhost:8080", "bucket", "", validator)
+		assert.Error(t, err)
+	})
+
+	t.Run("Memory Storage", func(t *testing.T) {
+		storage := NewMemoryStorageService("bucket", validator)
+		memory := storage.(*MemoryStorageService)
+
+		stored, err := storage.Upload(context.Background(), encoded.Bytes(), "posts/abc")
+		assert.NoError(t, err)
+		assert.Equal(t, storage.URL(stored.Key), stored.URL)
+
+		content, ok := memory.Get(stored.Key)
+		assert.True(t, ok)
+		assert.Equal(t, encoded.Bytes(), content)
+
+		_, err = storage.Upload(context.Background(), []byte("plain text"), "posts/abc")
+		assert.Error(t, err)
+		assert.Equal(t, 1, memory.Len())
+
+		assert.NoError(t, storage.Delete(context.Background(), stored.Key))
+		assert.Equal(t, 0, memory.Len())
+	})
+
+	t.Run("Reads Stop At The Upload Limit", func(t *testing.T) {
+		limited := NewUploadValidator(nil, 16, 0)
+		key := "2026/10/uploads/7-0123456789abcdef.png"
+
+		//Objects replaced by a larger one after they were checked
+		memory := NewMemoryStorageService("bucket", limited).(*MemoryStorageService)
+		memory.Put(key, make([]byte, 17))
+
+		dir := t.TempDir()
+		local, err := NewLocalStorageService(dir, "http://localhost:8080", "bucket", "secret", limited)
+		assert.NoError(t, err)
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "2026", "10", "uploads"), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, filepath.FromSlash(key)), make([]byte, 17), 0o644))
+
+		for _, storage := range []contracts.StorageRepository{memory, local} {
+			_, err := storage.Read(context.Background(), key)
+			assert.ErrorIs(t, err, contracts.ErrUploadTooLarge)
+		}
+
+		memory.Put(key, make([]byte, 16))
+		data, err := memory.Read(context.Background(), key)
+		assert.NoError(t, err)
+		assert.Len(t, data, 16)
+	})
+
+	t.Run("Legacy Cloud Storage Urls", func(t *testing.T) {
+		storage := NewGCStorageService("bucket", "", validator).(*GoogleCloudStorageService)
+
+		key, ok := storage.KeyFromURL(storage.URL("posts/a b.png"))
+		assert.True(t, ok)
+		assert.Equal(t, "posts/a b.png", key)
+
+		_, ok = storage.KeyFromURL("https://example.com/bucket/posts/a.png")
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStorageService keeps uploads in a map, meant for tests and local runs without a bucket
+type MemoryStorageService struct {
+	contracts.UploadValidatorContract
+	bucketName string
+	mu         sync.RWMutex
+	objects    map[string][]byte
+	updatedAt  map[string]time.Time
+}
+
+func (m *MemoryStorageService) UploadFile(ctx context.Context, file *multipart.FileHeader, name string) (contracts.StoredObject, error) {
+
+	fileBytes, err := m.ReadUpload(file)
+	if err != nil {
+		return contracts.StoredObject{}, err
+	}
+
+	return m.Upload(ctx, fileBytes, name)
+}
+
+func (m *MemoryStorageService) Upload(ctx context.Context, data []byte, name string) (contracts.StoredObject, error) {
+
+	contentType, err := m.Validate(data)
+	if err != nil {
+		return contracts.StoredObject{}, err
+	}
+
+	key := m.ObjectName(name, contentType)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = append([]byte(nil), data...)
+	m.updatedAt[key] = time.Now()
+
+	return contracts.StoredObject{Key: key, URL: m.URL(key), ContentType: contentType}, nil
+}
+
+func (m *MemoryStorageService) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	delete(m.updatedAt, key)
+	return nil
+}
+
+func (m *MemoryStorageService) PresignUpload(ctx context.Context, key string, contentType string, ttl time.Duration) (contracts.PresignedUpload, error) {
+	return contracts.PresignedUpload{
+		Key:       key,
+		URL:       m.URL(key),
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+func (m *MemoryStorageService) Stat(ctx context.Context, key string) (contracts.ObjectInfo, error) {
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data, ok := m.objects[key]
+	if !ok {
+		return contracts.ObjectInfo{}, contracts.ErrUploadNotFound
+	}
+
+	return contracts.ObjectInfo{Key: key, Size: int64(len(data)), ContentType: http.DetectContentType(data), UpdatedAt: m.updatedAt[key]}, nil
+}
+
+func (m *MemoryStorageService) List(ctx context.Context, prefix string, fn func(object contracts.ObjectInfo) error) error {
+
+	m.mu.RLock()
+	objects := make([]contracts.ObjectInfo, 0, len(m.objects))
+	for key, data := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, contracts.ObjectInfo{Key: key, Size: int64(len(data)), UpdatedAt: m.updatedAt[key]})
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	for _, object := range objects {
+		if err := fn(object); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetUpdatedAt overrides the modification time of the object
+func (m *MemoryStorageService) SetUpdatedAt(key string, updatedAt time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updatedAt[key] = updatedAt
+}
+
+func (m *MemoryStorageService) Read(ctx context.Context, key string) ([]byte, error) {
+
+	data, ok := m.Get(key)
+	if !ok {
+		return nil, contracts.ErrUploadNotFound
+	}
+
+	return m.ReadLimited(bytes.NewReader(data))
+}
+
+// Put stores data under the key as a client using a presigned upload would
+func (m *MemoryStorageService) Put(key string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = append([]byte(nil), data...)
+	m.updatedAt[key] = time.Now()
+}
+
+func (m *MemoryStorageService) URL(key string) string {
+	return "memory://" + m.bucketName + "/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+func (m *MemoryStorageService) Connect() error {
+	return nil
+}
+
+func (m *MemoryStorageService) Close() error {
+	return nil
+}
+
+// Get returns the stored bytes of the key
+func (m *MemoryStorageService) Get(key string) ([]byte, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.objects[key]
+	return data, ok
+}
+
+// Len returns the number of stored objects
+func (m *MemoryStorageService) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.objects)
+}
+
+func NewMemoryStorageService(bucketName string, validator contracts.UploadValidatorContract) contracts.StorageRepository {
+	return &MemoryStorageService{
+		UploadValidatorContract: validator,
+		bucketName:              bucketName,
+		objects:                 map[string][]byte{},
+		updatedAt:               map[string]time.Time{},
+	}
+}
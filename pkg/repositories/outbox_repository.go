@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/models"
+	"time"
+)
+
+type OutboxRepository struct {
+	Collection *mongo.Collection
+}
+
+// Enqueue joins the transaction of ctx when it is a session context
+func (o OutboxRepository) Enqueue(ctx context.Context, events ...models.OutboxEvent) error {
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	documents := make([]any, 0, len(events))
+	for _, event := range events {
+		documents = append(documents, event)
+	}
+
+	_, err := o.Collection.InsertMany(ctx, documents)
+	return err
+}
+
+func (o OutboxRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+
+	filter := bson.D{
+		{"status", models.OutboxStatusPending},
+		{"next_attempt_at", bson.D{{"$lte", now}}},
+	}
+	update := bson.D{{"$set", bson.D{{"next_attempt_at", now.Add(lease)}}}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{"next_attempt_at", 1}}).
+		SetReturnDocument(options.After)
+
+	events := make([]models.OutboxEvent, 0, limit)
+	for len(events) < limit {
+		var event models.OutboxEvent
+		err := o.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&event)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (o OutboxRepository) MarkDelivered(ctx context.Context, event models.OutboxEvent) error {
+
+	_, err := o.Collection.UpdateOne(ctx, bson.D{{"_id", event.ID}}, bson.D{
+		{"$set", bson.D{
+			{"status", models.OutboxStatusDelivered},
+			{"delivered_at", time.Now()},
+		}},
+		{"$inc", bson.D{{"attempts", 1}}},
+	})
+	return err
+}
+
+func (o OutboxRepository) MarkFailed(ctx context.Context, event models.OutboxEvent, nextAttemptAt time.Time, gaveUp bool) error {
+
+	set := bson.D{
+		{"status", models.OutboxStatusPending},
+		{"last_error", event.LastError},
+		{"next_attempt_at", nextAttemptAt},
+	}
+
+	//failed_at starts the expiry of events nobody is going to publish anymore
+	if gaveUp {
+		set[0].Value = models.OutboxStatusFailed
+		set = append(set, bson.E{Key: "failed_at", Value: time.Now()})
+	}
+
+	_, err := o.Collection.UpdateOne(ctx, bson.D{{"_id", event.ID}}, bson.D{
+		{"$set", set},
+		{"$inc", bson.D{{"attempts", 1}}},
+	})
+	return err
+}
+
+func NewOutboxRepository(collection *mongo.Collection) contracts.OutboxRepositoryContract {
+	return &OutboxRepository{Collection: collection}
+}
@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -11,11 +13,14 @@ import (
 	"golek_posts_service/pkg/contracts/status"
 	"golek_posts_service/pkg/models"
 	"log"
+	"time"
 )
 
 type DatabaseRepository struct {
-	Connection *mongo.Database
-	Collection *mongo.Collection
+	Connection       *mongo.Database
+	Collection       *mongo.Collection
+	OutboxCollection *mongo.Collection
+	AuditCollection  *mongo.Collection
 }
 
 func (d DatabaseRepository) Search(ctx context.Context, keyword string, limit int64, skip int64) ([]models.Post, error) {
@@ -24,7 +29,8 @@ func (d DatabaseRepository) Search(ctx context.Context, keyword string, limit in
 	opts.SetLimit(limit)
 	opts.SetSkip(skip)
 
-	filter := bson.D{{"$text", bson.D{{"$search", keyword}}}}
+	//Trashed posts stay out of the results until they are restored
+	filter := bson.D{{"$text", bson.D{{"$search", keyword}}}, {"deleted_at", nil}}
 	cursor, err := d.Collection.Find(ctx, filter, opts)
 	if err != nil {
 		return []models.Post{}, err
@@ -105,19 +111,58 @@ func (d DatabaseRepository) FindById(ctx context.Context, postID string) (models
 	return post, nil
 }
 
-func (d DatabaseRepository) Create(ctx context.Context, post models.Post) (models.Post, status.PostOperationStatus, error) {
+// FindByIDs loads the posts with one $in query and returns them in the order of postIDs.
+// Invalid, unknown and deleted ids are left out, duplicates are returned once
+func (d DatabaseRepository) FindByIDs(ctx context.Context, postIDs []string) ([]models.Post, error) {
 
-	//Open transaction
-	err := d.Connection.Client().UseSession(ctx, func(sessionContext mongo.SessionContext) error {
+	if len(postIDs) > contracts.MaxPostIDs {
+		return nil, fmt.Errorf("%w: at most %d, given %d", contracts.ErrTooManyPostIDs, contracts.MaxPostIDs, len(postIDs))
+	}
 
-		//Start Transaction
-		err := sessionContext.StartTransaction()
-		if err != nil {
-			return err
+	objectIDs := make([]primitive.ObjectID, 0, len(postIDs))
+	for _, postID := range postIDs {
+		if objectID, err := primitive.ObjectIDFromHex(postID); err == nil {
+			objectIDs = append(objectIDs, objectID)
+		}
+	}
+
+	if len(objectIDs) == 0 {
+		return []models.Post{}, nil
+	}
+
+	cursor, err := d.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": objectIDs}, "deleted_at": nil})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]models.Post, 0, len(objectIDs))
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]models.Post, len(found))
+	for _, post := range found {
+		byID[post.ID] = post
+	}
+
+	posts := make([]models.Post, 0, len(found))
+	for _, objectID := range objectIDs {
+		if post, ok := byID[objectID]; ok {
+			posts = append(posts, post)
+			delete(byID, objectID)
 		}
+	}
+
+	return posts, nil
+}
+
+// Create inserts the post and its outbox events in one transaction
+func (d DatabaseRepository) Create(ctx context.Context, post models.Post, events ...models.OutboxEvent) (models.Post, status.PostOperationStatus, error) {
+
+	err := d.withOutbox(ctx, events, func(sessionContext mongo.SessionContext) error {
 
 		//Insert Data
-		createdPost, err := d.Collection.InsertOne(ctx, post)
+		createdPost, err := d.Collection.InsertOne(sessionContext, post)
 		if err != nil {
 			return err
 		}
@@ -125,12 +170,6 @@ func (d DatabaseRepository) Create(ctx context.Context, post models.Post) (model
 		//attach id
 		post.SetPostID(createdPost.InsertedID.(primitive.ObjectID))
 
-		//Commit Transaction
-		err = sessionContext.CommitTransaction(ctx)
-		if err != nil {
-			return err
-		}
-
 		return nil
 	})
 	if err != nil {
@@ -140,7 +179,7 @@ func (d DatabaseRepository) Create(ctx context.Context, post models.Post) (model
 	return post, status.PostCreatedStatusSuccess, nil
 }
 
-func (d DatabaseRepository) Update(ctx context.Context, postID string, post models.Post) (models.Post, status.PostOperationStatus, error) {
+func (d DatabaseRepository) Update(ctx context.Context, postID string, post models.Post, events ...models.OutboxEvent) (models.Post, status.PostOperationStatus, error) {
 
 	//Convert PostID to Mongo ObjectID
 	objectId, err := primitive.ObjectIDFromHex(postID)
@@ -148,18 +187,61 @@ func (d DatabaseRepository) Update(ctx context.Context, postID string, post mode
 		return models.Post{}, status.PostUpdatedStatusFailed, err
 	}
 
-	//Query filter
-	filter := bson.D{{"_id", objectId}}
+	return d.update(ctx, bson.D{{"_id", objectId}}, post, nil, events)
+}
+
+// UpdateUnchanged updates the post only while its updated_at still equals unchangedSince,
+// a concurrent update in between fails with ErrPostStateConflict
+func (d DatabaseRepository) UpdateUnchanged(ctx context.Context, postID string, unchangedSince *time.Time, post models.Post, events ...models.OutboxEvent) (models.Post, status.PostOperationStatus, error) {
+
+	//Convert PostID to Mongo ObjectID
+	objectId, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return models.Post{}, status.PostUpdatedStatusFailed, err
+	}
+
+	//A post that was never updated has no updated_at, nil matches the missing field
+	return d.update(ctx, bson.D{{"_id", objectId}, {"updated_at", unchangedSince}}, post, contracts.ErrPostStateConflict, events)
+}
+
+// update writes the editable fields of the post, unmatched is returned when the filter matches nothing
+func (d DatabaseRepository) update(ctx context.Context, filter bson.D, post models.Post, unmatched error, events []models.OutboxEvent) (models.Post, status.PostOperationStatus, error) {
+
+	//Only editable fields are written, lifecycle fields change through Transition
+	update := bson.D{{"$set", bson.D{
+		{"title", post.Title},
+		{"image_url", post.ImageURL},
+		{"image_key", post.ImageKey},
+		{"images", post.Images},
+		{"cover_image_id", post.CoverImageID},
+		{"medium_image_url", post.MediumImageURL},
+		{"small_image_url", post.SmallImageURL},
+		{"place", post.Place},
+		{"description", post.Description},
+		{"characteristics", post.Characteristics},
+		{"updated_at", post.UpdatedAt},
+	}}}
 
 	//Query
-	_, err = d.Collection.UpdateOne(ctx, filter, bson.D{{"$set", post}})
+	err := d.withOutbox(ctx, events, func(sessionContext mongo.SessionContext) error {
+		updateResult, err := d.Collection.UpdateOne(sessionContext, filter, update)
+		if err != nil {
+			return err
+		}
+
+		if updateResult.MatchedCount == 0 {
+			return unmatched
+		}
+		return nil
+	})
 	if err != nil {
 		return models.Post{}, status.PostUpdatedStatusFailed, err
 	}
-	return post, status.PostUpdatedStatusSuccess, err
+	return post, status.PostUpdatedStatusSuccess, nil
 }
 
-func (d DatabaseRepository) Delete(ctx context.Context, postID string) (status.PostOperationStatus, error) {
+// Delete soft deletes the post, it stays restorable until it gets purged
+func (d DatabaseRepository) Delete(ctx context.Context, postID string, events ...models.OutboxEvent) (status.PostOperationStatus, error) {
 
 	//Convert PostID to Mongo ObjectID
 	objectID, err := primitive.ObjectIDFromHex(postID)
@@ -168,7 +250,115 @@ func (d DatabaseRepository) Delete(ctx context.Context, postID string) (status.P
 	}
 
 	//Query filter
-	filter := bson.D{{"_id", objectID}}
+	filter := bson.D{{"_id", objectID}, {"deleted_at", nil}}
+
+	//Query
+	err = d.withOutbox(ctx, events, func(sessionContext mongo.SessionContext) error {
+		updateResult, err := d.Collection.UpdateOne(sessionContext, filter, bson.D{{"$set", bson.D{{"deleted_at", time.Now()}}}})
+		if err != nil {
+			return err
+		}
+
+		if updateResult.MatchedCount == 0 {
+			return errors.New("unmatched any documents")
+		}
+		return nil
+	})
+	if err != nil {
+		return status.PostDeletedStatusFailed, err
+	}
+
+	return status.PostDeletedStatusSuccess, nil
+}
+
+func (d DatabaseRepository) FindTrashedById(ctx context.Context, postID string) (models.Post, error) {
+
+	var post models.Post
+
+	//Convert PostId to ObjectID
+	objectID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return post, err
+	}
+
+	//Set filters
+	filter := bson.D{{"_id", objectID}, {"deleted_at", bson.D{{"$ne", nil}}}}
+
+	//Decode
+	err = d.Collection.FindOne(ctx, filter).Decode(&post)
+	if err != nil {
+		return post, err
+	}
+
+	return post, nil
+}
+
+// FetchTrashed returns posts soft deleted before the given time, oldest first
+func (d DatabaseRepository) FetchTrashed(ctx context.Context, deletedBefore time.Time, limit int64) ([]models.Post, error) {
+
+	opts := options.Find()
+	opts.SetLimit(limit)
+	opts.SetSort(bson.D{{"deleted_at", 1}})
+
+	filter := bson.D{{"deleted_at", bson.D{{"$ne", nil}, {"$lt", deletedBefore}}}}
+
+	cursor, err := d.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]models.Post, 0)
+	if err = cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func (d DatabaseRepository) Restore(ctx context.Context, postID string, events ...models.OutboxEvent) (status.PostOperationStatus, error) {
+
+	//Convert PostID to Mongo ObjectID
+	objectID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return status.PostRestoredStatusFailed, err
+	}
+
+	//Query filter
+	filter := bson.D{{"_id", objectID}, {"deleted_at", bson.D{{"$ne", nil}}}}
+
+	//Query
+	err = d.withOutbox(ctx, events, func(sessionContext mongo.SessionContext) error {
+		updateResult, err := d.Collection.UpdateOne(sessionContext, filter, bson.D{{"$set", bson.D{
+			{"deleted_at", nil},
+			{"updated_at", time.Now()},
+		}}})
+		if err != nil {
+			return err
+		}
+
+		if updateResult.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return nil
+	})
+	if err != nil {
+		return status.PostRestoredStatusFailed, err
+	}
+
+	return status.PostRestoredStatusSuccess, nil
+}
+
+// Purge permanently removes a soft deleted post
+func (d DatabaseRepository) Purge(ctx context.Context, postID string) (status.PostOperationStatus, error) {
+
+	//Convert PostID to Mongo ObjectID
+	objectID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return status.PostDeletedStatusFailed, err
+	}
+
+	//Query filter
+	filter := bson.D{{"_id", objectID}, {"deleted_at", bson.D{{"$ne", nil}}}}
 
 	//Query
 	deleteResult, err := d.Collection.DeleteOne(ctx, filter)
@@ -183,6 +373,290 @@ func (d DatabaseRepository) Delete(ctx context.Context, postID string) (status.P
 	return status.PostDeletedStatusSuccess, nil
 }
 
-func NewPostRepository(connection *mongo.Database, collection *mongo.Collection) contracts.PostRepositoryContract {
-	return &DatabaseRepository{Connection: connection, Collection: collection}
+// Transition moves the post between lifecycle statuses. The update only applies while the post
+// is still in the expected status and matches the extra conditions, so concurrent transitions can't both win
+func (d DatabaseRepository) Transition(ctx context.Context, postID string, from models.PostStatus, to models.PostStatus, match map[string]any, set map[string]any, events ...models.OutboxEvent) (status.PostOperationStatus, error) {
+
+	//Convert PostID to Mongo ObjectID
+	objectID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return status.PostStatusUpdatedFailed, err
+	}
+
+	//Query filter
+	filter := bson.M{"_id": objectID, "status": from, "deleted_at": nil}
+	for key, value := range match {
+		filter[key] = value
+	}
+
+	fields := bson.M{"status": to, "updated_at": time.Now()}
+	for key, value := range set {
+		fields[key] = value
+	}
+
+	//Query
+	err = d.withOutbox(ctx, events, func(sessionContext mongo.SessionContext) error {
+		updateResult, err := d.Collection.UpdateOne(sessionContext, filter, bson.D{{"$set", fields}})
+		if err != nil {
+			return err
+		}
+
+		if updateResult.MatchedCount == 0 {
+			return contracts.ErrPostStateConflict
+		}
+		return nil
+	})
+	if err != nil {
+		return status.PostStatusUpdatedFailed, err
+	}
+
+	return status.PostStatusUpdatedSuccess, nil
+}
+
+// ExpireBefore marks open posts created before the given time as expired. Posts are expired in
+// batches, each batch and the events of its posts are written in one transaction
+func (d DatabaseRepository) ExpireBefore(ctx context.Context, createdBefore time.Time, eventOf func(expired models.Post) (models.OutboxEvent, error)) (int64, error) {
+
+	filter := bson.D{
+		{"status", models.PostStatusOpen},
+		{"deleted_at", nil},
+		{"created_at", bson.D{{"$lt", createdBefore}}},
+	}
+
+	var expired int64
+	for {
+		cursor, err := d.Collection.Find(ctx, filter, options.Find().SetLimit(100))
+		if err != nil {
+			return expired, err
+		}
+
+		posts := make([]models.Post, 0)
+		if err = cursor.All(ctx, &posts); err != nil {
+			return expired, err
+		}
+		if len(posts) == 0 {
+			return expired, nil
+		}
+
+		timeNow := time.Now()
+		postIDs := make([]primitive.ObjectID, 0, len(posts))
+		events := make([]models.OutboxEvent, 0, len(posts))
+		for _, post := range posts {
+			post.Status = models.PostStatusExpired
+			post.UpdatedAt = &timeNow
+
+			event, err := eventOf(post)
+			if err != nil {
+				return expired, err
+			}
+			postIDs = append(postIDs, post.ID)
+			events = append(events, event)
+		}
+
+		//A post claimed meanwhile is no longer open, its event is dropped with the transaction
+		var modified int64
+		err = d.withOutbox(ctx, events, func(sessionContext mongo.SessionContext) error {
+			batchFilter := append(bson.D{{"_id", bson.D{{"$in", postIDs}}}}, filter...)
+			updateResult, err := d.Collection.UpdateMany(sessionContext, batchFilter, bson.D{{"$set", bson.D{
+				{"status", models.PostStatusExpired},
+				{"updated_at", timeNow},
+			}}})
+			if err != nil {
+				return err
+			}
+			if updateResult.ModifiedCount != int64(len(postIDs)) {
+				return contracts.ErrPostStateConflict
+			}
+			modified = updateResult.ModifiedCount
+			return nil
+		})
+		if errors.Is(err, contracts.ErrPostStateConflict) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+
+		expired += modified
+	}
+}
+
+// SyncUserInfo replaces the embedded user of every post of the user. Posts already synced by an
+// event at or after syncedAt are left alone, so replayed and reordered events are harmless
+func (d DatabaseRepository) SyncUserInfo(ctx context.Context, userID int64, user models.UserInfo, syncedAt time.Time) (int64, error) {
+
+	filter := bson.D{
+		{"user_id", userID},
+		{"$or", bson.A{
+			bson.D{{"user.synced_at", bson.D{{"$exists", false}}}},
+			bson.D{{"user.synced_at", bson.D{{"$lt", syncedAt}}}},
+		}},
+	}
+
+	updateResult, err := d.Collection.UpdateMany(ctx, filter, bson.D{{"$set", bson.D{
+		{"user.username", user.Username},
+		{"user.usermajor", user.UserMajor},
+		{"user.synced_at", syncedAt},
+	}}})
+	if err != nil {
+		return 0, err
+	}
+
+	return updateResult.ModifiedCount, nil
+}
+
+// EachImageReference calls fn with the image fields of every post, soft deleted posts included
+func (d DatabaseRepository) EachImageReference(ctx context.Context, fn func(post models.Post) error) error {
+
+	opts := options.Find().SetProjection(bson.D{
+		{"image_url", 1},
+		{"image_key", 1},
+		{"images", 1},
+		{"cover_image_id", 1},
+	})
+
+	cursor, err := d.Collection.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var post models.Post
+		if err := cursor.Decode(&post); err != nil {
+			return err
+		}
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// Watch streams the changes of the posts matching the filter to fn until the context is done or fn fails.
+// A purged post has no document left, so its removal is not reported to watches filtered by user
+func (d DatabaseRepository) Watch(ctx context.Context, filter contracts.PostWatchFilter, resumeToken string, fn func(change contracts.PostChange) error) error {
+
+	match := bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}
+
+	if len(filter.PostIDs) > 0 {
+		objectIDs := make(bson.A, 0, len(filter.PostIDs))
+		for _, postID := range filter.PostIDs {
+			objectID, err := primitive.ObjectIDFromHex(postID)
+			if err != nil {
+				return err
+			}
+			objectIDs = append(objectIDs, objectID)
+		}
+		match["documentKey._id"] = bson.M{"$in": objectIDs}
+	}
+
+	if filter.UserID != 0 {
+		match["fullDocument.user_id"] = filter.UserID
+	}
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if resumeToken != "" {
+		token, err := base64.RawURLEncoding.DecodeString(resumeToken)
+		if err != nil || bson.Raw(token).Validate() != nil {
+			return contracts.ErrInvalidResumeToken
+		}
+		opts.SetResumeAfter(bson.Raw(token))
+	}
+
+	stream, err := d.Collection.Watch(ctx, mongo.Pipeline{{{"$match", match}}}, opts)
+	if err != nil {
+		return watchError(err)
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+
+		var event struct {
+			OperationType string              `bson:"operationType"`
+			ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+			FullDocument  *models.Post        `bson:"fullDocument"`
+			DocumentKey   struct {
+				ID primitive.ObjectID `bson:"_id"`
+			} `bson:"documentKey"`
+		}
+		if err := stream.Decode(&event); err != nil {
+			return err
+		}
+
+		change := contracts.PostChange{
+			Type:        contracts.PostChangeUpdated,
+			PostID:      event.DocumentKey.ID.Hex(),
+			Post:        event.FullDocument,
+			ResumeToken: base64.RawURLEncoding.EncodeToString(stream.ResumeToken()),
+			ChangedAt:   time.Unix(int64(event.ClusterTime.T), 0),
+		}
+
+		//Deleting only sets deleted_at, the document itself is gone once the post is purged
+		if event.OperationType == "insert" {
+			change.Type = contracts.PostChangeCreated
+		} else if event.FullDocument == nil || event.FullDocument.DeletedAt != nil {
+			change.Type = contracts.PostChangeDeleted
+		}
+
+		if err := fn(change); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return watchError(stream.Err())
+}
+
+// watchError reports resume tokens which fell out of the oplog
+func watchError(err error) error {
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && (serverErr.HasErrorCode(286) || serverErr.HasErrorCode(280)) {
+		return contracts.ErrResumeTokenExpired
+	}
+	return err
+}
+
+// withOutbox runs the write and stores the events and the audit entries of the context in one
+// transaction, so an event is published and audited if and only if the change it describes was committed
+func (d DatabaseRepository) withOutbox(ctx context.Context, events []models.OutboxEvent, write func(sessionContext mongo.SessionContext) error) error {
+
+	//Open transaction
+	return d.Connection.Client().UseSession(ctx, func(sessionContext mongo.SessionContext) error {
+
+		//Start Transaction
+		err := sessionContext.StartTransaction()
+		if err != nil {
+			return err
+		}
+
+		if err := write(sessionContext); err != nil {
+			_ = sessionContext.AbortTransaction(sessionContext)
+			return err
+		}
+
+		//Events are only published once the change is committed
+		err = OutboxRepository{Collection: d.OutboxCollection}.Enqueue(sessionContext, events...)
+		if err != nil {
+			_ = sessionContext.AbortTransaction(sessionContext)
+			return err
+		}
+
+		for _, audit := range contracts.AuditsOf(ctx) {
+			if err := (AuditRepository{Collection: d.AuditCollection}).Record(sessionContext, audit); err != nil {
+				_ = sessionContext.AbortTransaction(sessionContext)
+				return err
+			}
+		}
+
+		//Commit Transaction
+		return sessionContext.CommitTransaction(sessionContext)
+	})
+}
+
+func NewPostRepository(connection *mongo.Database, collection *mongo.Collection, outboxCollection *mongo.Collection, auditCollection *mongo.Collection) contracts.PostRepositoryContract {
+	return &DatabaseRepository{Connection: connection, Collection: collection, OutboxCollection: outboxCollection, AuditCollection: auditCollection}
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"golek_posts_service/pkg/contracts"
 	"golek_posts_service/pkg/contracts/status"
 	"golek_posts_service/pkg/database"
 	"golek_posts_service/pkg/models"
@@ -25,15 +26,16 @@ func TestDatabaseRepository(t *testing.T) {
 	}
 
 	db := database.Database{
-		DbName:       os.Getenv("DB_NAME"),
-		DbCollection: os.Getenv("DB_COLLECTION"),
-		DbHost:       os.Getenv("DB_HOST"),
-		DbPort:       os.Getenv("DB_PORT"),
-		DbUsername:   os.Getenv("DB_USERNAME"),
-		DBPassword:   os.Getenv("DB_PASSWORD"),
+		DbName:             os.Getenv("DB_NAME"),
+		DbCollection:       os.Getenv("DB_COLLECTION"),
+		DbOutboxCollection: "post_outbox",
+		DbHost:             os.Getenv("DB_HOST"),
+		DbPort:             os.Getenv("DB_PORT"),
+		DbUsername:         os.Getenv("DB_USERNAME"),
+		DBPassword:         os.Getenv("DB_PASSWORD"),
 	}
 	db.Prepare()
-	dbRepo := NewPostRepository(db.GetConnection(), db.GetCollection())
+	dbRepo := NewPostRepository(db.GetConnection(), db.GetCollection(), db.GetOutboxCollection(), db.GetAuditCollection())
 
 	t.Run("Fetch", func(t *testing.T) {
 		posts, err := dbRepo.Fetch(context.TODO(), false, 10, 0, map[string]any{})
@@ -50,6 +52,28 @@ func TestDatabaseRepository(t *testing.T) {
 		})
 	})
 
+	t.Run("FindByIDs", func(t *testing.T) {
+		timeNow := time.Now()
+
+		first, _, err := dbRepo.Create(context.TODO(), models.Post{ID: primitive.NewObjectID(), Title: "Post 1", CreatedAt: &timeNow})
+		assert.NoError(t, err)
+		second, _, err := dbRepo.Create(context.TODO(), models.Post{ID: primitive.NewObjectID(), Title: "Post 2", CreatedAt: &timeNow})
+		assert.NoError(t, err)
+
+		posts, err := dbRepo.FindByIDs(context.TODO(), []string{
+			second.ID.Hex(), "not-an-id", primitive.NewObjectID().Hex(), first.ID.Hex(), second.ID.Hex(),
+		})
+		assert.NoError(t, err)
+
+		//Input order is kept, unknown and invalid ids are skipped
+		assert.Len(t, posts, 2)
+		assert.Equal(t, second.ID, posts[0].ID)
+		assert.Equal(t, first.ID, posts[1].ID)
+
+		_, err = dbRepo.FindByIDs(context.TODO(), make([]string, contracts.MaxPostIDs+1))
+		assert.ErrorIs(t, err, contracts.ErrTooManyPostIDs)
+	})
+
 	t.Run("Create", func(t *testing.T) {
 
 		timeNow := time.Now()
@@ -1,17 +1,134 @@
 package repositories
 
 import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
 	"golek_posts_service/pkg/contracts"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	qrCodeMinSize = 64
+	qrCodeMaxSize = 1024
 )
 
 type QRCode struct {
+	size          int
+	recoveryLevel string
 }
 
+// Generate renders the text as PNG QR code with the default options
+// and returns it as data URI, so the payload never leaves the service
 func (q QRCode) Generate(text string) (url string, err error) {
 
-	return "https://api.qrserver.com/v1/create-qr-code/?size=150x150&data=" + text, nil
+	image, contentType, err := q.Render(text, contracts.QrCodeOptions{Format: contracts.QrCodeFormatPNG})
+	if err != nil {
+		return "", err
+	}
+
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(image), nil
+}
+
+func (q QRCode) Render(text string, options contracts.QrCodeOptions) (image []byte, contentType string, err error) {
+
+	options, err = q.Validate(options)
+	if err != nil {
+		return nil, "", err
+	}
+
+	level, _ := parseRecoveryLevel(options.RecoveryLevel)
+
+	code, err := qrcode.New(text, level)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch options.Format {
+	case contracts.QrCodeFormatPNG:
+		image, err = code.PNG(options.Size)
+		if err != nil {
+			return nil, "", err
+		}
+		return image, "image/png", nil
+	case contracts.QrCodeFormatSVG:
+		return renderSVG(code.Bitmap(), options.Size), "image/svg+xml", nil
+	default:
+		return nil, "", fmt.Errorf("%w: unsupported format %s", contracts.ErrInvalidQrCodeOptions, options.Format)
+	}
+}
+
+func (q QRCode) Validate(options contracts.QrCodeOptions) (contracts.QrCodeOptions, error) {
+
+	//Fallback to repository defaults
+	if options.Size == 0 {
+		options.Size = q.size
+	}
+	if options.RecoveryLevel == "" {
+		options.RecoveryLevel = q.recoveryLevel
+	}
+	if options.Format == "" {
+		options.Format = contracts.QrCodeFormatPNG
+	}
+
+	if options.Size < qrCodeMinSize || options.Size > qrCodeMaxSize {
+		return options, fmt.Errorf("%w: size must be between %d and %d", contracts.ErrInvalidQrCodeOptions, qrCodeMinSize, qrCodeMaxSize)
+	}
+
+	if options.Format != contracts.QrCodeFormatPNG && options.Format != contracts.QrCodeFormatSVG {
+		return options, fmt.Errorf("%w: unsupported format %s", contracts.ErrInvalidQrCodeOptions, options.Format)
+	}
+
+	if _, err := parseRecoveryLevel(options.RecoveryLevel); err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
+
+// renderSVG draws every dark module of the bitmap as a single path
+func renderSVG(bitmap [][]bool, size int) []byte {
+
+	var buf bytes.Buffer
+
+	modules := len(bitmap)
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/><path fill="#000000" d="`, modules, modules)
+
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes()
+}
+
+func parseRecoveryLevel(level string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToUpper(level) {
+	case "L":
+		return qrcode.Low, nil
+	case "", "M":
+		return qrcode.Medium, nil
+	case "Q":
+		return qrcode.High, nil
+	case "H":
+		return qrcode.Highest, nil
+	default:
+		return qrcode.Medium, fmt.Errorf("%w: unknown recovery level %s", contracts.ErrInvalidQrCodeOptions, level)
+	}
 }
 
-func NewQRCodeRepository() contracts.QrCodeRepository {
-	return &QRCode{}
+func NewQRCodeRepository(size int, recoveryLevel string) contracts.QrCodeRepository {
+	if size == 0 {
+		size = 256
+	}
+	return &QRCode{size: size, recoveryLevel: recoveryLevel}
 }
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"bytes"
+	"golek_posts_service/pkg/contracts"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQRCodeRepository(t *testing.T) {
+
+	repository := NewQRCodeRepository(0, "")
+
+	t.Run("Render PNG", func(t *testing.T) {
+		image, contentType, err := repository.Render("validation-data", contracts.QrCodeOptions{Size: 128})
+		assert.NoError(t, err)
+		assert.Equal(t, "image/png", contentType)
+
+		decoded, err := png.Decode(bytes.NewReader(image))
+		assert.NoError(t, err)
+		assert.Equal(t, 128, decoded.Bounds().Dx())
+	})
+
+	t.Run("Render SVG", func(t *testing.T) {
+		image, contentType, err := repository.Render("validation-data", contracts.QrCodeOptions{Format: contracts.QrCodeFormatSVG, RecoveryLevel: "h"})
+		assert.NoError(t, err)
+		assert.Equal(t, "image/svg+xml", contentType)
+		assert.True(t, strings.HasPrefix(string(image), `<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256"`))
+	})
+
+	t.Run("Reject Invalid Options", func(t *testing.T) {
+		for _, options := range []contracts.QrCodeOptions{
+			{Size: qrCodeMinSize - 1},
+			{Size: qrCodeMaxSize + 1},
+			{RecoveryLevel: "X"},
+			{Format: "gif"},
+		} {
+			_, err := repository.Validate(options)
+			assert.ErrorIs(t, err, contracts.ErrInvalidQrCodeOptions)
+
+			_, _, err = repository.Render("validation-data", options)
+			assert.ErrorIs(t, err, contracts.ErrInvalidQrCodeOptions)
+		}
+	})
+
+	t.Run("Validate Applies Defaults", func(t *testing.T) {
+		options, err := NewQRCodeRepository(512, "Q").Validate(contracts.QrCodeOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, contracts.QrCodeOptions{Format: contracts.QrCodeFormatPNG, Size: 512, RecoveryLevel: "Q"}, options)
+	})
+
+	t.Run("Render SVG Modules", func(t *testing.T) {
+		svg := string(renderSVG([][]bool{{true, false}, {false, true}}, 64))
+		assert.Contains(t, svg, `viewBox="0 0 2 2"`)
+		assert.Contains(t, svg, `d="M0 0h1v1h-1zM1 1h1v1h-1z"`)
+	})
+
+	t.Run("Parse Recovery Level", func(t *testing.T) {
+		for level, expected := range map[string]qrcode.RecoveryLevel{
+			"":  qrcode.Medium,
+			"l": qrcode.Low,
+			"M": qrcode.Medium,
+			"Q": qrcode.High,
+			"h": qrcode.Highest,
+		} {
+			parsed, err := parseRecoveryLevel(level)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, parsed)
+		}
+
+		_, err := parseRecoveryLevel("Z")
+		assert.ErrorIs(t, err, contracts.ErrInvalidQrCodeOptions)
+	})
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/exp/slices"
+	"golek_posts_service/pkg/contracts"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	unsafeObjectNameChars = regexp.MustCompile(`[^a-z0-9_\-/]+`)
+	repeatedSlashes       = regexp.MustCompile(`/{2,}`)
+)
+
+var contentTypeExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// UploadValidator is shared by every storage implementation so that nothing
+// reaches a bucket before its real content has been checked
+type UploadValidator struct {
+	allowedMimeTypes []string
+	maxSize          int64
+	//maxPixels bounds the canvas an image declares, a small file can decode to gigabytes
+	maxPixels int64
+}
+
+func (u UploadValidator) Validate(data []byte) (string, error) {
+
+	//Never trust the client supplied Content-Type header
+	contentType := http.DetectContentType(data)
+	if err := u.ValidateMeta(contentType, int64(len(data))); err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", contracts.ErrUploadCorrupted, err)
+		}
+		if int64(config.Width)*int64(config.Height) > u.maxPixels {
+			return "", fmt.Errorf("%w, maximum is %v pixels", contracts.ErrUploadTooLarge, u.maxPixels)
+		}
+	}
+
+	return contentType, nil
+}
+
+func (u UploadValidator) ValidateMeta(contentType string, size int64) error {
+
+	if size > u.maxSize {
+		return u.tooLarge()
+	}
+
+	if !slices.Contains(u.allowedMimeTypes, contentType) {
+		return fmt.Errorf("%w, given type %v", contracts.ErrUploadTypeNotAllowed, contentType)
+	}
+
+	return nil
+}
+
+// ObjectName keeps only a sanitized form of the hint and appends a random suffix,
+// so user input can neither traverse paths nor overwrite existing objects
+func (u UploadValidator) ObjectName(hint string, contentType string) string {
+
+	hint = unsafeObjectNameChars.ReplaceAllString(strings.ToLower(hint), "-")
+	hint = strings.Trim(repeatedSlashes.ReplaceAllString(hint, "/"), "-/")
+	if hint == "" {
+		hint = "upload"
+	}
+
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+
+	return time.Now().UTC().Format("2006/01/") + hint + "-" + hex.EncodeToString(suffix) + contentTypeExtensions[contentType]
+}
+
+func (u UploadValidator) ReadUpload(file *multipart.FileHeader) ([]byte, error) {
+
+	//The declared size is checked first, the limited read catches a lying client
+	if file.Size > u.maxSize {
+		return nil, u.tooLarge()
+	}
+
+	openedFile, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer openedFile.Close()
+
+	return u.ReadLimited(openedFile)
+}
+
+func (u UploadValidator) ReadLimited(reader io.Reader) ([]byte, error) {
+
+	data, err := ioutil.ReadAll(io.LimitReader(reader, u.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > u.maxSize {
+		return nil, u.tooLarge()
+	}
+
+	return data, nil
+}
+
+func (u UploadValidator) tooLarge() error {
+	return fmt.Errorf("%w, maximum file size is %v MB", contracts.ErrUploadTooLarge, u.maxSize/(1024*1024))
+}
+
+func NewUploadValidator(allowedMimeTypes []string, maxSize int64, maxPixels int64) contracts.UploadValidatorContract {
+
+	if len(allowedMimeTypes) == 0 {
+		allowedMimeTypes = []string{"image/jpeg", "image/png"}
+	}
+	if maxSize <= 0 {
+		maxSize = 5 * 1024 * 1024
+	}
+	if maxPixels <= 0 {
+		maxPixels = 40_000_000
+	}
+
+	return &UploadValidator{allowedMimeTypes: allowedMimeTypes, maxSize: maxSize, maxPixels: maxPixels}
+}
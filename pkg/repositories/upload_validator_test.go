@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"bytes"
+	"golek_posts_service/pkg/contracts"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadValidator(t *testing.T) {
+
+	var encoded bytes.Buffer
+	err := png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	assert.NoError(t, err)
+
+	validator := NewUploadValidator([]string{"image/png"}, 1024, 100)
+
+	t.Run("Valid Image", func(t *testing.T) {
+		contentType, err := validator.Validate(encoded.Bytes())
+		assert.NoError(t, err)
+		assert.Equal(t, "image/png", contentType)
+	})
+
+	t.Run("Too Large", func(t *testing.T) {
+		_, err := validator.Validate(make([]byte, 2048))
+		assert.ErrorIs(t, err, contracts.ErrUploadTooLarge)
+	})
+
+	t.Run("Sniffed Type Not Allowed", func(t *testing.T) {
+		_, err := validator.Validate([]byte("<html><script>alert(1)</script></html>"))
+		assert.ErrorIs(t, err, contracts.ErrUploadTypeNotAllowed)
+	})
+
+	t.Run("Corrupted Image", func(t *testing.T) {
+		_, err := validator.Validate(encoded.Bytes()[:20])
+		assert.ErrorIs(t, err, contracts.ErrUploadCorrupted)
+	})
+
+	t.Run("Too Many Pixels", func(t *testing.T) {
+		var wide bytes.Buffer
+		assert.NoError(t, png.Encode(&wide, image.NewGray(image.Rect(0, 0, 11, 10))))
+		assert.Less(t, wide.Len(), 1024)
+
+		_, err := validator.Validate(wide.Bytes())
+		assert.ErrorIs(t, err, contracts.ErrUploadTooLarge)
+	})
+
+	t.Run("Reads Uploads Up To The Maximum Size", func(t *testing.T) {
+		file := multipartFile(t, encoded.Bytes())
+		data, err := validator.ReadUpload(file)
+		assert.NoError(t, err)
+		assert.Equal(t, encoded.Bytes(), data)
+
+		_, err = validator.ReadUpload(multipartFile(t, make([]byte, 2048)))
+		assert.ErrorIs(t, err, contracts.ErrUploadTooLarge)
+
+		//A declared size below the real one does not get past the limited read
+		file = multipartFile(t, make([]byte, 2048))
+		file.Size = 10
+		_, err = validator.ReadUpload(file)
+		assert.ErrorIs(t, err, contracts.ErrUploadTooLarge)
+	})
+
+	t.Run("Safe Object Names", func(t *testing.T) {
+		name := validator.ObjectName("../../Secret Files/<kunci>", "image/png")
+		assert.NotContains(t, name, "..")
+		assert.NotContains(t, name, " ")
+		assert.True(t, strings.HasSuffix(name, ".png"))
+		assert.NotEqual(t, name, validator.ObjectName("../../Secret Files/<kunci>", "image/png"))
+	})
+}
+
+// multipartFile parses a form holding data as its only file
+func multipartFile(t *testing.T, data []byte) *multipart.FileHeader {
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("image", "image.png")
+	assert.NoError(t, err)
+	_, err = part.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	assert.NoError(t, err)
+
+	return form.File["image"][0]
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/http/requests"
+	"golek_posts_service/pkg/models"
+	"log"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ClaimService struct {
+	ClaimRepository contracts.ClaimRepositoryContract
+	PostRepository  contracts.PostRepositoryContract
+}
+
+func (c ClaimService) Submit(ctx context.Context, postID string, request requests.CreateClaimRequest) (models.Claim, status.PostOperationStatus, error) {
+
+	post, err := c.PostRepository.FindById(ctx, postID)
+	if err != nil {
+		return models.Claim{}, status.ClaimCreatedStatusFailed, err
+	}
+
+	//Claims are only accepted while the finder has not started a handover
+	if post.CurrentStatus() != models.PostStatusOpen && post.CurrentStatus() != models.PostStatusClaimPending {
+		return models.Claim{}, status.PostInvalidStateTransition, errors.New(
+			fmt.Sprintf("post in status %s does not accept claims", post.CurrentStatus()))
+	}
+
+	authenticatedReq := ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest)
+
+	//Checking authorization
+	opStatus, err := ProtectResource(
+		contracts.Resource{
+			Alias: "vp",
+			Name:  "Claim",
+		},
+		authenticatedReq,
+		post,
+		func(isOwner bool) (opStatus status.PostOperationStatus, err error) {
+			if isOwner {
+				return status.OperationForbidden, errors.New("owner can not claim their own post")
+			}
+			return status.OperationAllowed, nil
+		},
+	)
+
+	if err != nil {
+		return models.Claim{}, opStatus, err
+	}
+
+	//Every characteristic of the post has to be answered, in the same order
+	if len(request.Answers) != len(post.Characteristics) {
+		return models.Claim{}, status.ClaimAnswersInvalid, errors.New(
+			fmt.Sprintf("expected %d answers, given %d", len(post.Characteristics), len(request.Answers)))
+	}
+
+	userID, err := strconv.ParseInt(authenticatedReq.UserID, 10, 64)
+	if err != nil {
+		return models.Claim{}, status.ClaimCreatedStatusFailed, err
+	}
+
+	//A claimant may only have one open claim per post
+	_, err = c.ClaimRepository.FindOne(ctx, map[string]any{
+		"post_id":     post.ID,
+		"claimant_id": userID,
+		"status":      map[string]any{"$in": []models.ClaimStatus{models.ClaimStatusPending, models.ClaimStatusApproved}},
+	})
+	if err == nil {
+		return models.Claim{}, status.ClaimAlreadyExists, errors.New("claim already submitted")
+	}
+	if err != mongo.ErrNoDocuments {
+		return models.Claim{}, status.ClaimCreatedStatusFailed, err
+	}
+
+	answers := make([]models.ClaimAnswer, 0)
+	for i, d := range request.Answers {
+		answers = append(answers, models.ClaimAnswer{
+			Characteristic: post.Characteristics[i].Title,
+			Answer:         d.Answer,
+		})
+	}
+
+	timeNow := time.Now()
+
+	claim := models.Claim{
+		ID:         primitive.NewObjectID(),
+		PostID:     post.ID,
+		ClaimantID: userID,
+		Claimant: models.UserInfo{
+			Username:  authenticatedReq.Username,
+			UserMajor: authenticatedReq.UserMajor,
+		},
+		Answers:   answers,
+		Message:   request.Message,
+		Status:    models.ClaimStatusPending,
+		UpdatedAt: &timeNow,
+		CreatedAt: &timeNow,
+	}
+
+	//The first claim moves the post to claim_pending, a concurrent claim may have done it already
+	movesPost := post.CurrentStatus() == models.PostStatusOpen
+	var pendingEvent models.OutboxEvent
+	if movesPost {
+		pendingEvent, err = statusChangedEvent(ctx, post, models.PostStatusClaimPending)
+		if err != nil {
+			return models.Claim{}, status.ClaimCreatedStatusFailed, err
+		}
+	}
+
+	createdClaim, opStatus, err := c.ClaimRepository.Create(ctx, claim)
+	if err != nil || opStatus == status.ClaimCreatedStatusFailed {
+		return models.Claim{}, status.ClaimCreatedStatusFailed, err
+	}
+
+	if movesPost {
+		opStatus, err = transitionPost(ctx, c.PostRepository, post, models.PostStatusClaimPending, nil, nil, pendingEvent)
+		if err != nil && !errors.Is(err, contracts.ErrPostStateConflict) {
+			//Take the claim back, otherwise a retry is refused as a duplicate while the post stays open
+			if deleteErr := c.ClaimRepository.Delete(ctx, createdClaim.ID.Hex()); deleteErr != nil {
+				log.Printf("Claim Service: Submit >> remove claim %s: %v", createdClaim.ID.Hex(), deleteErr)
+			}
+			return models.Claim{}, opStatus, err
+		}
+	}
+
+	return createdClaim, status.ClaimCreatedStatusSuccess, nil
+}
+
+func (c ClaimService) ListByPost(ctx context.Context, postID string) ([]models.Claim, status.PostOperationStatus, error) {
+
+	post, err := c.PostRepository.FindById(ctx, postID)
+	if err != nil {
+		return nil, status.ClaimNotFound, err
+	}
+
+	authenticatedReq := ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest)
+
+	filter := map[string]any{"post_id": post.ID}
+
+	//The finder sees every claim, claimants only see their own
+	opStatus, err := ProtectResource(
+		contracts.Resource{
+			Alias: "vp",
+			Name:  "Claim",
+		},
+		authenticatedReq,
+		post,
+		func(isOwner bool) (opStatus status.PostOperationStatus, err error) {
+			if !isOwner {
+				userID, err := strconv.ParseInt(authenticatedReq.UserID, 10, 64)
+				if err != nil {
+					return status.OperationForbidden, err
+				}
+				filter["claimant_id"] = userID
+			}
+			return status.OperationAllowed, nil
+		},
+	)
+
+	if err != nil {
+		return nil, opStatus, err
+	}
+
+	claims, err := c.ClaimRepository.Fetch(ctx, filter)
+	if err != nil {
+		return nil, status.ClaimNotFound, err
+	}
+
+	return claims, status.OperationAllowed, nil
+}
+
+func (c ClaimService) Approve(ctx context.Context, postID string, claimID string) (models.Claim, status.PostOperationStatus, error) {
+
+	post, claim, opStatus, err := c.loadOwnedClaim(ctx, postID, claimID)
+	if err != nil {
+		return models.Claim{}, opStatus, err
+	}
+
+	if post.CurrentStatus() != models.PostStatusClaimPending {
+		return models.Claim{}, status.PostInvalidStateTransition, errors.New(
+			fmt.Sprintf("claims of a post in status %s can not be approved", post.CurrentStatus()))
+	}
+
+	//Only one claimant can be handed the item
+	_, err = c.ClaimRepository.FindOne(ctx, map[string]any{
+		"post_id": post.ID,
+		"status":  models.ClaimStatusApproved,
+	})
+	if err == nil {
+		return models.Claim{}, status.ClaimAlreadyApproved, errors.New("another claim is already approved")
+	}
+	if err != mongo.ErrNoDocuments {
+		return models.Claim{}, status.ClaimUpdatedStatusFailed, err
+	}
+
+	decidedBy, err := actingUserID(ctx)
+	if err != nil {
+		return models.Claim{}, status.ClaimUpdatedStatusFailed, err
+	}
+
+	return c.ClaimRepository.UpdateStatus(ctx, claim.ID.Hex(), models.ClaimStatusPending, models.ClaimStatusApproved, decidedBy)
+}
+
+func (c ClaimService) Reject(ctx context.Context, postID string, claimID string) (models.Claim, status.PostOperationStatus, error) {
+
+	post, claim, opStatus, err := c.loadOwnedClaim(ctx, postID, claimID)
+	if err != nil {
+		return models.Claim{}, opStatus, err
+	}
+
+	if claim.Status == models.ClaimStatusRejected {
+		return models.Claim{}, status.ClaimAlreadyDecided, errors.New("claim already rejected")
+	}
+
+	decidedBy, err := actingUserID(ctx)
+	if err != nil {
+		return models.Claim{}, status.ClaimUpdatedStatusFailed, err
+	}
+
+	rejected, opStatus, err := c.ClaimRepository.UpdateStatus(ctx, claim.ID.Hex(), claim.Status, models.ClaimStatusRejected, decidedBy)
+	if err != nil {
+		return models.Claim{}, opStatus, err
+	}
+
+	opStatus, err = c.settlePostStatus(ctx, post, claim)
+	if err != nil {
+		return models.Claim{}, opStatus, err
+	}
+
+	return rejected, status.ClaimUpdatedStatusSuccess, nil
+}
+
+// settlePostStatus moves the post back once the rejected claim no longer supports its status
+func (c ClaimService) settlePostStatus(ctx context.Context, post models.Post, rejected models.Claim) (status.PostOperationStatus, error) {
+
+	//Revoking the approved claim cancels the running handover and its QR code
+	if rejected.Status == models.ClaimStatusApproved && post.CurrentStatus() == models.PostStatusHandoverRequested {
+		pendingEvent, err := statusChangedEvent(ctx, post, models.PostStatusClaimPending)
+		if err != nil {
+			return status.ClaimUpdatedStatusFailed, err
+		}
+
+		opStatus, err := transitionPost(ctx, c.PostRepository, post, models.PostStatusClaimPending, nil,
+			map[string]any{"confirmation_key": ""}, pendingEvent)
+		if err != nil {
+			return opStatus, err
+		}
+		post.Status = models.PostStatusClaimPending
+	}
+
+	if post.CurrentStatus() != models.PostStatusClaimPending {
+		return status.ClaimUpdatedStatusSuccess, nil
+	}
+
+	//Without any open claim the post is open again
+	_, err := c.ClaimRepository.FindOne(ctx, map[string]any{
+		"post_id": post.ID,
+		"status":  map[string]any{"$in": []models.ClaimStatus{models.ClaimStatusPending, models.ClaimStatusApproved}},
+	})
+	if err == nil {
+		return status.ClaimUpdatedStatusSuccess, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return status.ClaimUpdatedStatusFailed, err
+	}
+
+	openEvent, err := statusChangedEvent(ctx, post, models.PostStatusOpen)
+	if err != nil {
+		return status.ClaimUpdatedStatusFailed, err
+	}
+
+	opStatus, err := transitionPost(ctx, c.PostRepository, post, models.PostStatusOpen, nil, nil, openEvent)
+	if err != nil && !errors.Is(err, contracts.ErrPostStateConflict) {
+		return opStatus, err
+	}
+
+	return status.ClaimUpdatedStatusSuccess, nil
+}
+
+// loadOwnedClaim loads a claim of the post and ensures the authenticated user owns the post
+func (c ClaimService) loadOwnedClaim(ctx context.Context, postID string, claimID string) (models.Post, models.Claim, status.PostOperationStatus, error) {
+
+	post, err := c.PostRepository.FindById(ctx, postID)
+	if err != nil {
+		return models.Post{}, models.Claim{}, status.ClaimNotFound, err
+	}
+
+	opStatus, err := ProtectResource(
+		contracts.Resource{
+			Alias: "vp",
+			Name:  "Claim",
+		},
+		ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest),
+		post,
+		func(isOwner bool) (opStatus status.PostOperationStatus, err error) {
+			if !isOwner {
+				return status.OperationForbidden, errors.New(
+					fmt.Sprintf("User x is not the owner of Model y"))
+			}
+			return status.OperationAllowed, nil
+		},
+	)
+
+	if err != nil {
+		return models.Post{}, models.Claim{}, opStatus, err
+	}
+
+	claim, err := c.ClaimRepository.FindById(ctx, claimID)
+	if err != nil || claim.PostID != post.ID {
+		return models.Post{}, models.Claim{}, status.ClaimNotFound, mongo.ErrNoDocuments
+	}
+
+	return post, claim, status.OperationAllowed, nil
+}
+
+// actingUserID is the id of the authenticated user deciding on a claim
+func actingUserID(ctx context.Context) (int64, error) {
+	authenticatedReq := ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest)
+	return strconv.ParseInt(authenticatedReq.UserID, 10, 64)
+}
+
+func NewClaimService(claimRepository *contracts.ClaimRepositoryContract, postRepository *contracts.PostRepositoryContract) contracts.ClaimServiceContract {
+	return &ClaimService{
+		ClaimRepository: *claimRepository,
+		PostRepository:  *postRepository,
+	}
+}
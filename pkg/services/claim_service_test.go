@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/http/requests"
+	"golek_posts_service/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryClaims applies status updates conditionally and allows one approved claim per post,
+// like the claims collection and its partial unique index
+type memoryClaims struct {
+	contracts.ClaimRepositoryContract
+	claims map[primitive.ObjectID]*models.Claim
+	//staleReads hides approved claims from FindOne, as a concurrent approval would
+	staleReads bool
+}
+
+func (m *memoryClaims) FindById(ctx context.Context, claimID string) (models.Claim, error) {
+	objectID, _ := primitive.ObjectIDFromHex(claimID)
+	if claim, ok := m.claims[objectID]; ok {
+		return *claim, nil
+	}
+	return models.Claim{}, mongo.ErrNoDocuments
+}
+
+func (m *memoryClaims) FindOne(ctx context.Context, filter map[string]any) (models.Claim, error) {
+	for _, claim := range m.claims {
+		if claim.PostID != filter["post_id"] {
+			continue
+		}
+		if claimantID, ok := filter["claimant_id"]; ok && claim.ClaimantID != claimantID {
+			continue
+		}
+		if claim.Status == models.ClaimStatusApproved && !m.staleReads {
+			return *claim, nil
+		}
+		if claim.Status == models.ClaimStatusPending && filter["status"] != models.ClaimStatusApproved {
+			return *claim, nil
+		}
+	}
+	return models.Claim{}, mongo.ErrNoDocuments
+}
+
+func (m *memoryClaims) Create(ctx context.Context, claim models.Claim) (models.Claim, status.PostOperationStatus, error) {
+	m.claims[claim.ID] = &claim
+	return claim, status.ClaimCreatedStatusSuccess, nil
+}
+
+func (m *memoryClaims) Delete(ctx context.Context, claimID string) error {
+	objectID, _ := primitive.ObjectIDFromHex(claimID)
+	delete(m.claims, objectID)
+	return nil
+}
+
+func (m *memoryClaims) UpdateStatus(ctx context.Context, claimID string, from models.ClaimStatus, to models.ClaimStatus, decidedBy int64) (models.Claim, status.PostOperationStatus, error) {
+	objectID, _ := primitive.ObjectIDFromHex(claimID)
+	claim, ok := m.claims[objectID]
+	if !ok || claim.Status != from {
+		return models.Claim{}, status.ClaimAlreadyDecided, errors.New("claim is no longer " + string(from))
+	}
+	if to == models.ClaimStatusApproved {
+		for _, other := range m.claims {
+			if other.PostID == claim.PostID && other.Status == models.ClaimStatusApproved {
+				return models.Claim{}, status.ClaimAlreadyApproved, errors.New("another claim is already approved")
+			}
+		}
+	}
+	claim.Status = to
+	claim.DecidedBy = decidedBy
+	return *claim, status.ClaimUpdatedStatusSuccess, nil
+}
+
+// transitioningPosts serves one post and records the transitions made on it
+type transitioningPosts struct {
+	contracts.PostRepositoryContract
+	post        models.Post
+	transitions []models.PostStatus
+	events      []models.OutboxEvent
+	//transitionErr fails every transition, as an unavailable database would
+	transitionErr error
+}
+
+func (t *transitioningPosts) FindById(ctx context.Context, postID string) (models.Post, error) {
+	return t.post, nil
+}
+
+func (t *transitioningPosts) Transition(ctx context.Context, postID string, from models.PostStatus, to models.PostStatus, match map[string]any, set map[string]any, events ...models.OutboxEvent) (status.PostOperationStatus, error) {
+	if t.transitionErr != nil {
+		return status.PostStatusUpdatedFailed, t.transitionErr
+	}
+	t.transitions = append(t.transitions, to)
+	t.events = append(t.events, events...)
+	return status.PostStatusUpdatedSuccess, nil
+}
+
+func TestClaimService(t *testing.T) {
+
+	post := models.Post{ID: primitive.NewObjectID(), UserID: 7, Status: models.PostStatusClaimPending}
+	ctx := context.WithValue(context.Background(), "authenticatedRequest",
+		&middleware.AuthenticatedRequest{UserID: "7", Role: "user", Permissions: "vp"})
+
+	setup := func(claimStatuses ...models.ClaimStatus) (ClaimService, *memoryClaims, *transitioningPosts, []models.Claim) {
+		claims := &memoryClaims{claims: map[primitive.ObjectID]*models.Claim{}}
+		created := make([]models.Claim, 0, len(claimStatuses))
+		for i, claimStatus := range claimStatuses {
+			claim := models.Claim{ID: primitive.NewObjectID(), PostID: post.ID, ClaimantID: int64(10 + i), Status: claimStatus}
+			claims.claims[claim.ID] = &claim
+			created = append(created, claim)
+		}
+		posts := &transitioningPosts{post: post}
+		return ClaimService{ClaimRepository: claims, PostRepository: posts}, claims, posts, created
+	}
+
+	t.Run("Approve Records The Acting User", func(t *testing.T) {
+		service, _, _, claims := setup(models.ClaimStatusPending)
+
+		approved, opStatus, err := service.Approve(ctx, post.ID.Hex(), claims[0].ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, status.ClaimUpdatedStatusSuccess, opStatus)
+		assert.Equal(t, models.ClaimStatusApproved, approved.Status)
+		assert.Equal(t, int64(7), approved.DecidedBy)
+	})
+
+	t.Run("Only One Concurrent Approval Wins", func(t *testing.T) {
+		service, repository, _, claims := setup(models.ClaimStatusPending, models.ClaimStatusPending)
+		repository.staleReads = true
+
+		_, _, err := service.Approve(ctx, post.ID.Hex(), claims[0].ID.Hex())
+		assert.NoError(t, err)
+
+		_, opStatus, err := service.Approve(ctx, post.ID.Hex(), claims[1].ID.Hex())
+		assert.Error(t, err)
+		assert.Equal(t, status.ClaimAlreadyApproved, opStatus)
+	})
+
+	t.Run("Rejecting Twice Conflicts", func(t *testing.T) {
+		service, _, _, claims := setup(models.ClaimStatusRejected)
+
+		_, opStatus, err := service.Reject(ctx, post.ID.Hex(), claims[0].ID.Hex())
+		assert.Error(t, err)
+		assert.Equal(t, status.ClaimAlreadyDecided, opStatus)
+	})
+
+	t.Run("Rejecting The Last Claim Reopens The Post", func(t *testing.T) {
+		service, _, posts, claims := setup(models.ClaimStatusPending)
+
+		rejected, _, err := service.Reject(ctx, post.ID.Hex(), claims[0].ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), rejected.DecidedBy)
+		assert.Equal(t, []models.PostStatus{models.PostStatusOpen}, posts.transitions)
+		assert.Len(t, posts.events, 1)
+		assert.Equal(t, "post.updated.v1", posts.events[0].RoutingKey)
+	})
+
+	t.Run("Only The Owner Decides", func(t *testing.T) {
+		service, _, _, claims := setup(models.ClaimStatusPending)
+		claimant := context.WithValue(context.Background(), "authenticatedRequest",
+			&middleware.AuthenticatedRequest{UserID: "10", Role: "user", Permissions: "vp"})
+
+		_, opStatus, err := service.Approve(claimant, post.ID.Hex(), claims[0].ID.Hex())
+		assert.Error(t, err)
+		assert.Equal(t, status.OperationForbidden, opStatus)
+	})
+
+	t.Run("Submit Takes The Claim Back When The Post Stays Open", func(t *testing.T) {
+		service, repository, posts, _ := setup()
+		posts.post = models.Post{ID: post.ID, UserID: 7, Status: models.PostStatusOpen,
+			Characteristics: []models.Characteristic{{Title: "Color"}}}
+		posts.transitionErr = errors.New("server selection timeout")
+		claimant := context.WithValue(context.Background(), "authenticatedRequest",
+			&middleware.AuthenticatedRequest{UserID: "10", Role: "user", Permissions: "vp"})
+		request := requests.CreateClaimRequest{Answers: []requests.ClaimAnswerRequest{{Answer: "Black"}}}
+
+		_, _, err := service.Submit(claimant, post.ID.Hex(), request)
+		assert.Error(t, err)
+		assert.Empty(t, repository.claims)
+
+		//The retry is not refused as a duplicate
+		posts.transitionErr = nil
+		created, opStatus, err := service.Submit(claimant, post.ID.Hex(), request)
+		assert.NoError(t, err)
+		assert.Equal(t, status.ClaimCreatedStatusSuccess, opStatus)
+		assert.Equal(t, models.ClaimStatusPending, created.Status)
+		assert.Equal(t, []models.PostStatus{models.PostStatusClaimPending}, posts.transitions)
+	})
+}
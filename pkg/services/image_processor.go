@@ -0,0 +1,287 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"golek_posts_service/pkg/contracts"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// ImageProcessor decodes uploaded photos and re-encodes them without any metadata.
+// Only the pixels survive, so EXIF data such as GPS coordinates is never published
+type ImageProcessor struct {
+	maxDimension    int
+	mediumDimension int
+	smallDimension  int
+	quality         int
+	//maxPixels bounds the decoded size, a small file can declare a huge canvas
+	maxPixels int
+}
+
+func (i ImageProcessor) Process(data []byte) ([]contracts.ProcessedImage, error) {
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "jpeg" && format != "png") {
+		return nil, contracts.ErrUnsupportedImage
+	}
+	if int64(config.Width)*int64(config.Height) > int64(i.maxPixels) {
+		return nil, fmt.Errorf("%w, maximum is %v pixels", contracts.ErrImageTooLarge, i.maxPixels)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, contracts.ErrUnsupportedImage
+	}
+
+	img := toNRGBA(decoded)
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	variants := []struct {
+		variant   contracts.ImageVariant
+		dimension int
+	}{
+		{contracts.ImageVariantOriginal, i.maxDimension},
+		{contracts.ImageVariantMedium, i.mediumDimension},
+		{contracts.ImageVariantSmall, i.smallDimension},
+	}
+
+	processed := make([]contracts.ProcessedImage, 0, len(variants))
+
+	for _, v := range variants {
+		resized := fit(img, v.dimension)
+
+		var buf bytes.Buffer
+		contentType := "image/jpeg"
+
+		if format == "png" {
+			contentType = "image/png"
+			err = png.Encode(&buf, resized)
+		} else {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: i.quality})
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		processed = append(processed, contracts.ProcessedImage{
+			Variant:     v.variant,
+			Data:        buf.Bytes(),
+			ContentType: contentType,
+			Width:       resized.Bounds().Dx(),
+			Height:      resized.Bounds().Dy(),
+		})
+	}
+
+	return processed, nil
+}
+
+func toNRGBA(src image.Image) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+	return dst
+}
+
+// fit scales the image down, keeping its aspect ratio, until the longest side
+// is at most maxDimension. Smaller images are returned untouched
+func fit(src *image.NRGBA, maxDimension int) *image.NRGBA {
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if maxDimension <= 0 || (w <= maxDimension && h <= maxDimension) {
+		return src
+	}
+
+	dw, dh := maxDimension, h*maxDimension/w
+	if h > w {
+		dw, dh = w*maxDimension/h, maxDimension
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	//Box filter: every destination pixel averages the source pixels it covers
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, (y+1)*h/dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, (x+1)*w/dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					alpha := uint64(src.Pix[offset+3])
+					r += uint64(src.Pix[offset]) * alpha
+					g += uint64(src.Pix[offset+1]) * alpha
+					b += uint64(src.Pix[offset+2]) * alpha
+					a += alpha
+					n++
+					offset += 4
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			if a > 0 {
+				dst.Pix[offset] = uint8(r / a)
+				dst.Pix[offset+1] = uint8(g / a)
+				dst.Pix[offset+2] = uint8(b / a)
+			}
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+// orient rotates and flips the pixels so the image looks upright without the
+// EXIF orientation tag, which is dropped when re-encoding
+func orient(src *image.NRGBA, orientation int) *image.NRGBA {
+
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+
+	return dst
+}
+
+// jpegOrientation reads the orientation tag from the EXIF segment of a jpeg, 1 when absent
+func jpegOrientation(data []byte) int {
+
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+
+		//Start of scan, no metadata follows
+		if marker == 0xDA {
+			return 1
+		}
+
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+
+		i += 2 + length
+	}
+
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < entries; e++ {
+		entry := ifd + 2 + e*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+
+	return 1
+}
+
+func NewImageProcessor(maxDimension int, mediumDimension int, smallDimension int, quality int, maxPixels int) contracts.ImageProcessorContract {
+
+	if maxDimension <= 0 {
+		maxDimension = 2048
+	}
+	if mediumDimension <= 0 {
+		mediumDimension = 640
+	}
+	if smallDimension <= 0 {
+		smallDimension = 200
+	}
+	if quality <= 0 || quality > 100 {
+		quality = 85
+	}
+	if maxPixels <= 0 {
+		maxPixels = 40_000_000
+	}
+
+	return &ImageProcessor{
+		maxDimension:    maxDimension,
+		mediumDimension: mediumDimension,
+		smallDimension:  smallDimension,
+		quality:         quality,
+		maxPixels:       maxPixels,
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// jpegWithExif encodes a w x h jpeg whose top left pixel is red and inserts an
+// EXIF segment carrying the orientation tag and a fake GPS marker
+func jpegWithExif(t *testing.T, w int, h int, orientation uint16) []byte {
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{B: 255, A: 255})
+		}
+	}
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	var encoded bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 100}))
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint16(tiff[18:], orientation)
+	payload := append(append([]byte("Exif\x00\x00"), tiff...), []byte("GPSLatitude-7.2575")...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	data := encoded.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestImageProcessor(t *testing.T) {
+
+	processor := NewImageProcessor(400, 100, 40, 90, 1000*1000)
+
+	t.Run("Strips Metadata And Creates Thumbnails", func(t *testing.T) {
+		data := jpegWithExif(t, 800, 600, 1)
+		assert.Equal(t, 1, jpegOrientation(data))
+
+		processed, err := processor.Process(data)
+		assert.NoError(t, err)
+		assert.Len(t, processed, 3)
+
+		expected := map[contracts.ImageVariant][2]int{
+			contracts.ImageVariantOriginal: {400, 300},
+			contracts.ImageVariantMedium:   {100, 75},
+			contracts.ImageVariantSmall:    {40, 30},
+		}
+
+		for _, p := range processed {
+			assert.Equal(t, "image/jpeg", p.ContentType)
+			assert.Equal(t, expected[p.Variant], [2]int{p.Width, p.Height})
+			assert.False(t, bytes.Contains(p.Data, []byte("Exif")))
+			assert.False(t, bytes.Contains(p.Data, []byte("GPSLatitude")))
+		}
+	})
+
+	t.Run("Applies Orientation", func(t *testing.T) {
+		//Orientation 6 means the camera was rotated, the stored top left ends up top right
+		data := jpegWithExif(t, 200, 100, 6)
+		assert.Equal(t, 6, jpegOrientation(data))
+
+		processed, err := processor.Process(data)
+		assert.NoError(t, err)
+		assert.Equal(t, [2]int{100, 200}, [2]int{processed[0].Width, processed[0].Height})
+
+		decoded, err := jpeg.Decode(bytes.NewReader(processed[0].Data))
+		assert.NoError(t, err)
+
+		r, _, b, _ := decoded.At(97, 2).RGBA()
+		assert.Greater(t, r, b)
+		r, _, b, _ = decoded.At(2, 2).RGBA()
+		assert.Greater(t, b, r)
+	})
+
+	t.Run("Rejects Too Many Pixels Before Decoding", func(t *testing.T) {
+		var encoded bytes.Buffer
+		assert.NoError(t, png.Encode(&encoded, image.NewGray(image.Rect(0, 0, 1001, 1000))))
+
+		_, err := processor.Process(encoded.Bytes())
+		assert.ErrorIs(t, err, contracts.ErrImageTooLarge)
+		assert.Equal(t, status.PostUploadTooLarge, uploadErrorStatus(err, status.PostCreatedStatusFailed))
+	})
+
+	t.Run("Rejects Unsupported Data", func(t *testing.T) {
+		_, err := processor.Process([]byte("GIF89a not really an image"))
+		assert.ErrorIs(t, err, contracts.ErrUnsupportedImage)
+	})
+}
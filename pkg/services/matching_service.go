@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/models"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	titleWeight          = 0.5
+	characteristicWeight = 0.3
+	placeWeight          = 0.2
+)
+
+// stopWords are dropped before comparing texts, they appear in most posts
+var stopWords = map[string]bool{
+	"di": true, "ke": true, "dan": true, "yang": true, "dari": true, "dekat": true, "depan": true,
+	"ada": true, "warna": true, "the": true, "and": true, "near": true, "with": true, "of": true,
+}
+
+type MatchingService struct {
+	PostRepository contracts.PostRepositoryContract
+	candidateLimit int64
+	minScore       float64
+}
+
+// FindMatches scores open posts of the opposite type against the given post
+// by the similarity of their title, characteristics and place
+func (m MatchingService) FindMatches(ctx context.Context, post models.Post, limit int) ([]models.PostMatch, error) {
+
+	filter := map[string]any{
+		"_id":        map[string]any{"$ne": post.ID},
+		"type":       post.Type.Opposite(),
+		"status":     map[string]any{"$in": []models.PostStatus{models.PostStatusOpen, models.PostStatusClaimPending}},
+		"deleted_at": nil,
+	}
+
+	candidates, err := m.PostRepository.Fetch(ctx, true, m.candidateLimit, 0, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]models.PostMatch, 0)
+	for _, candidate := range candidates {
+		score := ScorePostMatch(post, candidate)
+		//Posts sharing nothing never match, whatever the configured minimum
+		if score > 0 && score >= m.minScore {
+			matches = append(matches, models.PostMatch{Post: candidate, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches, nil
+}
+
+// ScorePostMatch returns a similarity between 0 and 1
+func ScorePostMatch(a models.Post, b models.Post) float64 {
+
+	score := titleWeight * similarity(tokenize(a.Title), tokenize(b.Title))
+	score += characteristicWeight * similarity(tokenize(joinCharacteristics(a)), tokenize(joinCharacteristics(b)))
+	score += placeWeight * similarity(tokenize(a.Place), tokenize(b.Place))
+
+	return score
+}
+
+func joinCharacteristics(post models.Post) string {
+	titles := make([]string, 0, len(post.Characteristics))
+	for _, c := range post.Characteristics {
+		titles = append(titles, c.Title)
+	}
+	return strings.Join(titles, " ")
+}
+
+func tokenize(text string) map[string]bool {
+
+	tokens := map[string]bool{}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		if len(word) < 2 || stopWords[word] {
+			continue
+		}
+		tokens[word] = true
+	}
+
+	return tokens
+}
+
+// similarity is the Sørensen–Dice coefficient of two token sets
+func similarity(a map[string]bool, b map[string]bool) float64 {
+
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for token := range a {
+		if b[token] {
+			shared++
+		}
+	}
+
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
+
+func NewMatchingService(postRepository *contracts.PostRepositoryContract, candidateLimit int64, minScore float64) contracts.MatchingServiceContract {
+
+	if candidateLimit <= 0 {
+		candidateLimit = 200
+	}
+	if minScore <= 0 {
+		minScore = 0.2
+	}
+
+	return &MatchingService{
+		PostRepository: *postRepository,
+		candidateLimit: candidateLimit,
+		minScore:       minScore,
+	}
+}
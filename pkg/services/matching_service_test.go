@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScorePostMatch(t *testing.T) {
+
+	lost := models.Post{
+		Type:            models.PostTypeLost,
+		Title:           "Dompet kulit coklat",
+		Place:           "Kantin gedung A",
+		Characteristics: []models.Characteristic{{Title: "Ada KTM"}, {Title: "Warna coklat tua"}},
+	}
+
+	t.Run("Similar Post", func(t *testing.T) {
+		found := models.Post{
+			Type:            models.PostTypeFound,
+			Title:           "Dompet coklat",
+			Place:           "Kantin gedung A",
+			Characteristics: []models.Characteristic{{Title: "Warna coklat"}},
+		}
+
+		score := ScorePostMatch(lost, found)
+		assert.Greater(t, score, 0.5)
+		assert.LessOrEqual(t, score, 1.0)
+	})
+
+	t.Run("Unrelated Post", func(t *testing.T) {
+		found := models.Post{
+			Type:            models.PostTypeFound,
+			Title:           "Samsung A35",
+			Place:           "Lantai 2 depan ruang dosen",
+			Characteristics: []models.Characteristic{{Title: "Casing hitam"}},
+		}
+
+		assert.Equal(t, 0.0, ScorePostMatch(lost, found))
+	})
+
+	t.Run("Identical Post", func(t *testing.T) {
+		assert.InDelta(t, 1.0, ScorePostMatch(lost, lost), 0.0001)
+	})
+
+	t.Run("Never Matches Unrelated Posts", func(t *testing.T) {
+		unrelated := models.Post{Type: models.PostTypeFound, Title: "Samsung A35", Place: "Lantai 2"}
+		similar := models.Post{Type: models.PostTypeFound, Title: "Dompet coklat", Place: "Kantin gedung A"}
+
+		var postRepository contracts.PostRepositoryContract = candidatePosts{posts: []models.Post{unrelated, similar}}
+		matches, err := NewMatchingService(&postRepository, 0, 0).FindMatches(context.Background(), lost, 10)
+		assert.NoError(t, err)
+		assert.Len(t, matches, 1)
+		assert.Equal(t, "Dompet coklat", matches[0].Post.Title)
+	})
+}
+
+// candidatePosts returns the same candidates for every query
+type candidatePosts struct {
+	contracts.PostRepositoryContract
+	posts []models.Post
+}
+
+func (c candidatePosts) Fetch(ctx context.Context, latest bool, limit int64, skip int64, filter map[string]any) ([]models.Post, error) {
+	return c.posts, nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golek_posts_service/pkg/contracts"
+	"log"
+	"time"
+)
+
+// OutboxRelay publishes the events stored in the outbox and retries failed ones with an
+// exponential backoff. Delivery is at least once, consumers must tolerate duplicates
+type OutboxRelay struct {
+	OutboxRepository contracts.OutboxRepositoryContract
+	MessageQueue     contracts.MessageQueue
+	batchSize        int
+	maxAttempts      int
+	baseBackoff      time.Duration
+	maxBackoff       time.Duration
+	lease            time.Duration
+	now              func() time.Time
+}
+
+func (o OutboxRelay) Relay(ctx context.Context) (int, error) {
+
+	//Attempts are only spent while the broker is reachable
+	if !o.MessageQueue.Healthy() {
+		return 0, nil
+	}
+
+	events, err := o.OutboxRepository.ClaimDue(ctx, o.now(), o.lease, o.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, event := range events {
+
+		if err := o.MessageQueue.Publish(event.RoutingKey, event.Payload); err != nil {
+			//The claimed events are picked up again once their lease ends
+			if errors.Is(err, contracts.ErrBrokerUnavailable) {
+				return delivered, nil
+			}
+
+			//Nobody is bound to the routing key, retrying won't change that
+			event.LastError = err.Error()
+			gaveUp := event.Attempts+1 >= o.maxAttempts || errors.Is(err, contracts.ErrMessageUnroutable)
+			if gaveUp {
+				log.Printf("Outbox Relay: gave up on event %s after %d attempts: %v", event.ID.Hex(), event.Attempts+1, err)
+			}
+
+			if err := o.OutboxRepository.MarkFailed(ctx, event, o.now().Add(o.backoff(event.Attempts)), gaveUp); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		//A failure here only causes the event to be published again once the lease ends
+		if err := o.OutboxRepository.MarkDelivered(ctx, event); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+
+	return delivered, nil
+}
+
+// backoff doubles the delay for every failed attempt up to maxBackoff
+func (o OutboxRelay) backoff(attempts int) time.Duration {
+	delay := o.baseBackoff
+	for i := 0; i < attempts && delay < o.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > o.maxBackoff {
+		delay = o.maxBackoff
+	}
+	return delay
+}
+
+func NewOutboxRelay(outboxRepository *contracts.OutboxRepositoryContract, messageQueue *contracts.MessageQueue, batchSize int, maxAttempts int) contracts.OutboxRelayContract {
+
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+
+	return &OutboxRelay{
+		OutboxRepository: *outboxRepository,
+		MessageQueue:     *messageQueue,
+		batchSize:        batchSize,
+		maxAttempts:      maxAttempts,
+		baseBackoff:      time.Second,
+		maxBackoff:       10 * time.Minute,
+		lease:            time.Minute,
+		now:              time.Now,
+	}
+}
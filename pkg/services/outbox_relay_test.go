@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golek_posts_service/cmd/msg_broker"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryOutbox struct {
+	events map[primitive.ObjectID]*models.OutboxEvent
+}
+
+func (m *memoryOutbox) Enqueue(ctx context.Context, events ...models.OutboxEvent) error {
+	for i := range events {
+		m.events[events[i].ID] = &events[i]
+	}
+	return nil
+}
+
+func (m *memoryOutbox) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+	claimed := make([]models.OutboxEvent, 0)
+	for _, event := range m.events {
+		if event.Status == models.OutboxStatusPending && !event.NextAttemptAt.After(now) && len(claimed) < limit {
+			event.NextAttemptAt = now.Add(lease)
+			claimed = append(claimed, *event)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *memoryOutbox) MarkDelivered(ctx context.Context, event models.OutboxEvent) error {
+	m.events[event.ID].Status = models.OutboxStatusDelivered
+	m.events[event.ID].Attempts++
+	return nil
+}
+
+func (m *memoryOutbox) MarkFailed(ctx context.Context, event models.OutboxEvent, nextAttemptAt time.Time, gaveUp bool) error {
+	stored := m.events[event.ID]
+	stored.Attempts++
+	stored.LastError = event.LastError
+	stored.NextAttemptAt = nextAttemptAt
+	if gaveUp {
+		stored.Status = models.OutboxStatusFailed
+	}
+	return nil
+}
+
+func TestOutboxRelay(t *testing.T) {
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	outbox := &memoryOutbox{events: map[primitive.ObjectID]*models.OutboxEvent{}}
+	broker := msg_broker.NewMemoryBroker()
+	broker.FailWith(errors.New("broker unavailable"))
+
+	var outboxRepository contracts.OutboxRepositoryContract = outbox
+	var messageQueue contracts.MessageQueue = broker
+	relay := NewOutboxRelay(&outboxRepository, &messageQueue, 10, 3).(*OutboxRelay)
+	relay.now = func() time.Time { return now }
+
+	event := models.NewOutboxEvent(primitive.NewObjectID(), contracts.NewPostRoutingKey, []byte("new post"))
+	event.NextAttemptAt = now
+	assert.NoError(t, outbox.Enqueue(context.Background(), event))
+
+	t.Run("Keeps Event While Broker Is Down", func(t *testing.T) {
+		delivered, err := relay.Relay(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, delivered)
+
+		stored := outbox.events[event.ID]
+		assert.Equal(t, models.OutboxStatusPending, stored.Status)
+		assert.Equal(t, 1, stored.Attempts)
+		assert.Equal(t, "broker unavailable", stored.LastError)
+		assert.Equal(t, now.Add(time.Second), stored.NextAttemptAt)
+
+		//Not due yet
+		delivered, err = relay.Relay(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, delivered)
+		assert.Equal(t, 1, stored.Attempts)
+	})
+
+	t.Run("Delivers Once Broker Is Back", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		broker.FailWith(nil)
+
+		delivered, err := relay.Relay(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, delivered)
+		published := broker.Messages(contracts.NewPostRoutingKey)
+		assert.Len(t, published, 1)
+		assert.Equal(t, "new post", string(published[0].Payload))
+		assert.Equal(t, models.OutboxStatusDelivered, outbox.events[event.ID].Status)
+	})
+
+	t.Run("Gives Up After Max Attempts", func(t *testing.T) {
+		broker.FailWith(errors.New("broker unavailable"))
+		failing := models.NewOutboxEvent(primitive.NewObjectID(), contracts.NewPostRoutingKey, []byte("lost"))
+		failing.NextAttemptAt = now
+		assert.NoError(t, outbox.Enqueue(context.Background(), failing))
+
+		for i := 0; i < 3; i++ {
+			_, err := relay.Relay(context.Background())
+			assert.NoError(t, err)
+			now = now.Add(time.Hour)
+		}
+
+		assert.Equal(t, models.OutboxStatusFailed, outbox.events[failing.ID].Status)
+		assert.Equal(t, 3, outbox.events[failing.ID].Attempts)
+	})
+
+	t.Run("Gives Up On Unroutable Events", func(t *testing.T) {
+		broker.FailWith(contracts.ErrMessageUnroutable)
+		unroutable := models.NewOutboxEvent(primitive.NewObjectID(), "post.unknown.v1", []byte("nobody listens"))
+		unroutable.NextAttemptAt = now
+		assert.NoError(t, outbox.Enqueue(context.Background(), unroutable))
+
+		_, err := relay.Relay(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, models.OutboxStatusFailed, outbox.events[unroutable.ID].Status)
+		assert.Equal(t, 1, outbox.events[unroutable.ID].Attempts)
+	})
+
+	t.Run("Waits While Broker Is Unavailable", func(t *testing.T) {
+		broker.FailWith(contracts.ErrBrokerUnavailable)
+		waiting := models.NewOutboxEvent(primitive.NewObjectID(), contracts.NewPostRoutingKey, []byte("later"))
+		waiting.NextAttemptAt = now
+		assert.NoError(t, outbox.Enqueue(context.Background(), waiting))
+
+		_, err := relay.Relay(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, outbox.events[waiting.ID].Attempts)
+		assert.Equal(t, models.OutboxStatusPending, outbox.events[waiting.ID].Status)
+	})
+
+	t.Run("Backoff Is Capped", func(t *testing.T) {
+		assert.Equal(t, 4*time.Second, relay.backoff(2))
+		assert.Equal(t, 10*time.Minute, relay.backoff(50))
+	})
+}
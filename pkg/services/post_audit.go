@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/models"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// auditIgnoredFields are either bookkeeping or secrets that must not be copied into the trail
+var auditIgnoredFields = map[string]bool{
+	"_id":              true,
+	"updated_at":       true,
+	"confirmation_key": true,
+}
+
+// DiffPosts returns every stored field whose value differs between both versions of a post
+func DiffPosts(before models.Post, after models.Post) ([]models.FieldChange, error) {
+
+	beforeFields, err := postFields(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterFields, err := postFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(afterFields))
+	for key := range afterFields {
+		keys = append(keys, key)
+	}
+	for key := range beforeFields {
+		if _, ok := afterFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]models.FieldChange, 0)
+	for _, key := range keys {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		if !reflect.DeepEqual(beforeFields[key], afterFields[key]) {
+			changes = append(changes, models.FieldChange{
+				Field: key,
+				Old:   beforeFields[key],
+				New:   afterFields[key],
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+func postFields(post models.Post) (bson.M, error) {
+
+	raw, err := bson.Marshal(post)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := bson.M{}
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// audited attaches the history entry of the change to the context, the repository stores it together
+// with the change so a failing audit fails the change as well
+func audited(ctx context.Context, operation models.AuditOperation, before models.Post, after models.Post) (context.Context, error) {
+
+	changes, err := DiffPosts(before, after)
+	if err != nil {
+		return ctx, err
+	}
+
+	entry := models.PostAudit{
+		ID:        primitive.NewObjectID(),
+		PostID:    after.ID,
+		Operation: operation,
+		Changes:   changes,
+		CreatedAt: time.Now(),
+	}
+
+	if authenticatedReq, ok := ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest); ok && authenticatedReq != nil {
+		entry.ActorID, _ = strconv.ParseInt(authenticatedReq.UserID, 10, 64)
+		entry.ActorRole = authenticatedReq.Role
+	}
+
+	return contracts.WithAudit(ctx, entry), nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDiffPosts(t *testing.T) {
+
+	before := models.Post{
+		ID:              primitive.NewObjectID(),
+		Title:           "Samsung A35",
+		Place:           "Lantai 2",
+		ConfirmationKey: "old-nonce",
+		Characteristics: []models.Characteristic{{Title: "Casing hitam"}},
+	}
+
+	t.Run("Changed Fields Only", func(t *testing.T) {
+		after := before
+		after.Title = "Samsung A35 5G"
+		after.ConfirmationKey = "new-nonce"
+
+		changes, err := DiffPosts(before, after)
+		if err != nil {
+			t.Error(err)
+		}
+
+		assert.Len(t, changes, 1)
+		assert.Equal(t, "title", changes[0].Field)
+		assert.Equal(t, "Samsung A35", changes[0].Old)
+		assert.Equal(t, "Samsung A35 5G", changes[0].New)
+	})
+
+	t.Run("No Changes", func(t *testing.T) {
+		changes, err := DiffPosts(before, before)
+		if err != nil {
+			t.Error(err)
+		}
+
+		assert.Empty(t, changes)
+	})
+
+	t.Run("Audited Attaches The Entry", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), "authenticatedRequest",
+			&middleware.AuthenticatedRequest{UserID: "7", Role: "admin"})
+		after := before
+		after.Place = "Lantai 3"
+
+		auditCtx, err := audited(ctx, models.AuditOperationUpdate, before, after)
+		assert.NoError(t, err)
+		assert.Empty(t, contracts.AuditsOf(ctx))
+
+		audits := contracts.AuditsOf(auditCtx)
+		assert.Len(t, audits, 1)
+		assert.Equal(t, before.ID, audits[0].PostID)
+		assert.Equal(t, models.AuditOperationUpdate, audits[0].Operation)
+		assert.Equal(t, int64(7), audits[0].ActorID)
+		assert.Equal(t, "admin", audits[0].ActorRole)
+		assert.Equal(t, "place", audits[0].Changes[0].Field)
+	})
+}
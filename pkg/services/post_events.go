@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/models"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// postEvent wraps the post in a versioned event ready to be stored in the outbox
+func postEvent(ctx context.Context, eventType contracts.PostEventType, before models.Post, after models.Post) (models.OutboxEvent, error) {
+
+	event := contracts.PostEvent{
+		ID:         primitive.NewObjectID().Hex(),
+		Type:       eventType,
+		Version:    contracts.PostEventVersion,
+		OccurredAt: time.Now().UTC(),
+		Actor:      eventActor(ctx),
+		Post:       contracts.NewPostEventData(after),
+	}
+
+	if eventType == contracts.PostEventUpdated {
+		changes, err := DiffPosts(before, after)
+		if err != nil {
+			return models.OutboxEvent{}, err
+		}
+		event.ChangedFields = make([]string, 0, len(changes))
+		for _, change := range changes {
+			event.ChangedFields = append(event.ChangedFields, change.Field)
+		}
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return models.OutboxEvent{}, err
+	}
+
+	outboxEvent := models.NewOutboxEvent(after.ID, event.RoutingKey(), payload)
+	outboxEvent.ID, _ = primitive.ObjectIDFromHex(event.ID)
+
+	return outboxEvent, nil
+}
+
+// statusChangedEvent is the update event of a post moving to another lifecycle status
+func statusChangedEvent(ctx context.Context, post models.Post, to models.PostStatus) (models.OutboxEvent, error) {
+	after := post
+	after.Status = to
+	return postEvent(ctx, contracts.PostEventUpdated, post, after)
+}
+
+func eventActor(ctx context.Context) contracts.EventActor {
+	if authenticatedReq, ok := ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest); ok && authenticatedReq != nil {
+		userID, _ := strconv.ParseInt(authenticatedReq.UserID, 10, 64)
+		return contracts.EventActor{UserID: userID, Role: authenticatedReq.Role}
+	}
+	return contracts.EventActor{Role: "system"}
+}
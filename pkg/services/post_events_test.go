@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPostEvent(t *testing.T) {
+
+	before := models.Post{
+		ID:     primitive.NewObjectID(),
+		UserID: 7,
+		Type:   models.PostTypeFound,
+		Status: models.PostStatusOpen,
+		Title:  "Samsung A35",
+		Place:  "Lantai 2",
+	}
+	after := before
+	after.Title = "Samsung A35 hitam"
+
+	ctx := context.WithValue(context.Background(), "authenticatedRequest", &middleware.AuthenticatedRequest{UserID: "7", Role: "user"})
+
+	t.Run("Updated Event", func(t *testing.T) {
+		outboxEvent, err := postEvent(ctx, contracts.PostEventUpdated, before, after)
+		assert.NoError(t, err)
+		assert.Equal(t, "post.updated.v1", outboxEvent.RoutingKey)
+		assert.Equal(t, after.ID, outboxEvent.AggregateID)
+
+		var event contracts.PostEvent
+		assert.NoError(t, json.Unmarshal(outboxEvent.Payload, &event))
+		assert.Equal(t, outboxEvent.ID.Hex(), event.ID)
+		assert.Equal(t, contracts.PostEventVersion, event.Version)
+		assert.Equal(t, contracts.EventActor{UserID: 7, Role: "user"}, event.Actor)
+		assert.Equal(t, "Samsung A35 hitam", event.Post.Title)
+		assert.Equal(t, []string{"title"}, event.ChangedFields)
+		assert.False(t, event.OccurredAt.IsZero())
+	})
+
+	t.Run("System Actor Without Request", func(t *testing.T) {
+		outboxEvent, err := postEvent(context.Background(), contracts.PostEventDeleted, before, before)
+		assert.NoError(t, err)
+		assert.Equal(t, "post.deleted.v1", outboxEvent.RoutingKey)
+
+		var event contracts.PostEvent
+		assert.NoError(t, json.Unmarshal(outboxEvent.Payload, &event))
+		assert.Equal(t, contracts.EventActor{Role: "system"}, event.Actor)
+		assert.Empty(t, event.ChangedFields)
+	})
+
+	t.Run("Status Changed Event", func(t *testing.T) {
+		outboxEvent, err := statusChangedEvent(ctx, before, models.PostStatusArchived)
+		assert.NoError(t, err)
+		assert.Equal(t, "post.updated.v1", outboxEvent.RoutingKey)
+
+		var event contracts.PostEvent
+		assert.NoError(t, json.Unmarshal(outboxEvent.Payload, &event))
+		assert.Equal(t, models.PostStatusArchived, event.Post.Status)
+		assert.Equal(t, []string{"status"}, event.ChangedFields)
+	})
+
+	t.Run("Expired Posts Carry Their Events", func(t *testing.T) {
+		repository := &expiringPosts{posts: []models.Post{before}}
+		var postRepository contracts.PostRepositoryContract = repository
+
+		expired, err := PostService{PostRepository: postRepository}.ExpireStale(context.Background(), time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), expired)
+		assert.Len(t, repository.events, 1)
+
+		var event contracts.PostEvent
+		assert.NoError(t, json.Unmarshal(repository.events[0].Payload, &event))
+		assert.Equal(t, models.PostStatusExpired, event.Post.Status)
+		assert.Equal(t, contracts.EventActor{Role: "system"}, event.Actor)
+	})
+}
+
+// expiringPosts expires its posts and keeps the events it would store with them
+type expiringPosts struct {
+	contracts.PostRepositoryContract
+	posts  []models.Post
+	events []models.OutboxEvent
+}
+
+func (e *expiringPosts) ExpireBefore(ctx context.Context, createdBefore time.Time, eventOf func(expired models.Post) (models.OutboxEvent, error)) (int64, error) {
+	for _, post := range e.posts {
+		post.Status = models.PostStatusExpired
+		event, err := eventOf(post)
+		if err != nil {
+			return 0, err
+		}
+		e.events = append(e.events, event)
+	}
+	return int64(len(e.posts)), nil
+}
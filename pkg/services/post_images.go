@@ -0,0 +1,432 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/http/requests"
+	"golek_posts_service/pkg/models"
+	"log"
+	"mime/multipart"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (p PostService) AddImages(ctx context.Context, postID string, request requests.AddPostImagesRequest) (models.Post, status.PostOperationStatus, error) {
+
+	post, opStatus, err := p.loadEditablePost(ctx, postID)
+	if err != nil {
+		return models.Post{}, opStatus, err
+	}
+
+	if len(request.Images) == 0 || len(post.Images)+len(request.Images) > p.MaxImages {
+		return models.Post{}, status.PostImagesInvalid, errors.New(
+			fmt.Sprintf("a post can have at most %d images", p.MaxImages))
+	}
+
+	uploaded, err := p.uploadImages(ctx, request.Images)
+	if err != nil {
+		return models.Post{}, uploadErrorStatus(err, status.PostImagesUpdatedFailed), err
+	}
+
+	updated := post
+	updated.Images = append(append([]models.PostImage(nil), post.Images...), uploaded...)
+
+	updatedPost, opStatus, err := p.saveImages(ctx, post, updated)
+	if err != nil {
+		p.deleteImages(ctx, uploaded)
+		return models.Post{}, opStatus, err
+	}
+
+	return updatedPost, opStatus, nil
+}
+
+func (p PostService) RemoveImage(ctx context.Context, postID string, imageID string) (models.Post, status.PostOperationStatus, error) {
+
+	post, opStatus, err := p.loadEditablePost(ctx, postID)
+	if err != nil {
+		return models.Post{}, opStatus, err
+	}
+
+	removed, ok := post.FindImage(imageID)
+	if !ok {
+		return models.Post{}, status.PostImagesInvalid, errors.New("image " + imageID + " does not belong to the post")
+	}
+
+	if len(post.Images) == 1 {
+		return models.Post{}, status.PostImagesInvalid, errors.New("the last image of a post can not be removed")
+	}
+
+	updated := post
+	updated.Images = make([]models.PostImage, 0, len(post.Images)-1)
+	for _, image := range post.Images {
+		if image.ID != imageID {
+			updated.Images = append(updated.Images, image)
+		}
+	}
+
+	updatedPost, opStatus, err := p.saveImages(ctx, post, updated)
+	if err != nil {
+		return models.Post{}, opStatus, err
+	}
+
+	p.deleteImages(ctx, []models.PostImage{removed})
+
+	return updatedPost, opStatus, nil
+}
+
+func (p PostService) ReorderImages(ctx context.Context, postID string, request requests.ReorderPostImagesRequest) (models.Post, status.PostOperationStatus, error) {
+
+	post, opStatus, err := p.loadEditablePost(ctx, postID)
+	if err != nil {
+		return models.Post{}, opStatus, err
+	}
+
+	//The new order has to name every image of the post exactly once
+	if len(request.ImageIDs) != len(post.Images) {
+		return models.Post{}, status.PostImagesInvalid, errors.New(
+			fmt.Sprintf("expected %d image ids, given %d", len(post.Images), len(request.ImageIDs)))
+	}
+
+	updated := post
+	updated.Images = make([]models.PostImage, 0, len(post.Images))
+	seen := map[string]bool{}
+
+	for _, imageID := range request.ImageIDs {
+		image, ok := post.FindImage(imageID)
+		if !ok || seen[imageID] {
+			return models.Post{}, status.PostImagesInvalid, errors.New("image " + imageID + " is unknown or repeated")
+		}
+		seen[imageID] = true
+		updated.Images = append(updated.Images, image)
+	}
+
+	return p.saveImages(ctx, post, updated)
+}
+
+func (p PostService) SetCoverImage(ctx context.Context, postID string, imageID string) (models.Post, status.PostOperationStatus, error) {
+
+	post, opStatus, err := p.loadEditablePost(ctx, postID)
+	if err != nil {
+		return models.Post{}, opStatus, err
+	}
+
+	if _, ok := post.FindImage(imageID); !ok {
+		return models.Post{}, status.PostImagesInvalid, errors.New("image " + imageID + " does not belong to the post")
+	}
+
+	updated := post
+	updated.Images = append([]models.PostImage(nil), post.Images...)
+	updated.CoverImageID = imageID
+
+	return p.saveImages(ctx, post, updated)
+}
+
+// loadEditablePost loads the post and ensures the authenticated user owns it
+func (p PostService) loadEditablePost(ctx context.Context, postID string) (models.Post, status.PostOperationStatus, error) {
+
+	post, err := p.PostRepository.FindById(ctx, postID)
+	if err != nil {
+		return models.Post{}, status.PostImagesUpdatedFailed, err
+	}
+
+	opStatus, err := ProtectResource(
+		contracts.Resource{
+			Alias: "u",
+			Name:  "Update",
+		},
+		ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest),
+		post,
+		func(isOwner bool) (opStatus status.PostOperationStatus, err error) {
+			if !isOwner {
+				return status.OperationForbidden, errors.New(
+					fmt.Sprintf("User x is not the owner of Model y"))
+			}
+			return status.OperationAllowed, nil
+		},
+	)
+
+	if err != nil {
+		return models.Post{}, opStatus, err
+	}
+
+	opStatus, err = ensureEditable(post)
+	if err != nil {
+		return models.Post{}, opStatus, err
+	}
+
+	return post, status.OperationAllowed, nil
+}
+
+func (p PostService) saveImages(ctx context.Context, before models.Post, post models.Post) (models.Post, status.PostOperationStatus, error) {
+
+	timeNow := time.Now()
+	post.UpdatedAt = &timeNow
+	post.ArrangeImages()
+
+	updatedEvent, err := postEvent(ctx, contracts.PostEventUpdated, before, post)
+	if err != nil {
+		return models.Post{}, status.PostImagesUpdatedFailed, err
+	}
+
+	auditCtx, err := audited(ctx, models.AuditOperationUpdate, before, post)
+	if err != nil {
+		return models.Post{}, status.PostImagesUpdatedFailed, err
+	}
+
+	//The images are rewritten as a whole, so another edit since loading the post must not be overwritten
+	updatedPost, opStatus, err := p.PostRepository.UpdateUnchanged(auditCtx, post.ID.Hex(), before.UpdatedAt, post, updatedEvent)
+	if errors.Is(err, contracts.ErrPostStateConflict) {
+		return models.Post{}, status.PostImagesConflict, err
+	}
+	if err != nil || opStatus == status.PostUpdatedStatusFailed {
+		return models.Post{}, status.PostImagesUpdatedFailed, err
+	}
+
+	return updatedPost, status.PostImagesUpdatedSuccess, nil
+}
+
+// uploadImages processes and stores the files in the given order together with their
+// thumbnails. When one upload fails the already stored images are removed again
+func (p PostService) uploadImages(ctx context.Context, files []*multipart.FileHeader) ([]models.PostImage, error) {
+
+	images := make([]models.PostImage, 0, len(files))
+
+	for i, file := range files {
+		image, err := p.uploadImage(ctx, file)
+		if err != nil {
+			p.deleteImages(ctx, images)
+			return nil, err
+		}
+
+		image.Order = i
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+func (p PostService) uploadImage(ctx context.Context, file *multipart.FileHeader) (models.PostImage, error) {
+
+	data, err := p.StorageRepository.ReadUpload(file)
+	if err != nil {
+		return models.PostImage{}, err
+	}
+
+	return p.storeImage(ctx, data)
+}
+
+// storeImage validates and processes the raw image and stores every variant of it
+func (p PostService) storeImage(ctx context.Context, data []byte) (models.PostImage, error) {
+
+	//Reject the raw upload before spending time on decoding it
+	if _, err := p.StorageRepository.Validate(data); err != nil {
+		return models.PostImage{}, err
+	}
+
+	processed, err := p.ImageProcessor.Process(data)
+	if err != nil {
+		return models.PostImage{}, err
+	}
+
+	image := models.PostImage{ID: primitive.NewObjectID().Hex()}
+
+	//Object names never contain user input
+	name := "posts/" + image.ID
+
+	for _, variant := range processed {
+		objectName := name
+		if variant.Variant != contracts.ImageVariantOriginal {
+			objectName = name + "_" + string(variant.Variant)
+		}
+
+		stored, err := p.StorageRepository.Upload(ctx, variant.Data, objectName)
+		if err != nil {
+			p.deleteImages(ctx, []models.PostImage{image})
+			return models.PostImage{}, err
+		}
+
+		switch variant.Variant {
+		case contracts.ImageVariantOriginal:
+			image.Key, image.URL = stored.Key, stored.URL
+		case contracts.ImageVariantMedium:
+			image.MediumKey, image.MediumURL = stored.Key, stored.URL
+		case contracts.ImageVariantSmall:
+			image.SmallKey, image.SmallURL = stored.Key, stored.URL
+		}
+	}
+
+	return image, nil
+}
+
+// PresignUploads issues urls the client can upload images to without streaming them through this service.
+// The returned keys are passed to Create as upload_keys afterwards
+func (p PostService) PresignUploads(ctx context.Context, request requests.PresignUploadRequest) ([]contracts.PresignedUpload, status.PostOperationStatus, error) {
+
+	authenticatedReq := ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest)
+
+	opStatus, err := ProtectResource(
+		contracts.Resource{
+			Alias: "c",
+			Name:  "Create",
+		},
+		authenticatedReq,
+		models.Post{},
+		func(isOwner bool) (opStatus status.PostOperationStatus, err error) {
+			return status.OperationAllowed, nil
+		},
+	)
+	if err != nil {
+		return nil, opStatus, err
+	}
+
+	count := request.Count
+	if count == 0 {
+		count = 1
+	}
+	if count > p.MaxImages {
+		return nil, status.PostImagesInvalid, errors.New(
+			fmt.Sprintf("a post can have at most %d images", p.MaxImages))
+	}
+
+	if err := p.StorageRepository.ValidateMeta(request.ContentType, request.Size); err != nil {
+		return nil, uploadErrorStatus(err, status.PostUploadPresignedFailed), err
+	}
+
+	uploads := make([]contracts.PresignedUpload, 0, count)
+	for i := 0; i < count; i++ {
+		key := p.StorageRepository.ObjectName(pendingUploadPrefix(authenticatedReq.UserID), request.ContentType)
+
+		upload, err := p.StorageRepository.PresignUpload(ctx, key, request.ContentType, presignedUploadTTL)
+		if err != nil {
+			return nil, status.PostUploadPresignedFailed, err
+		}
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, status.PostUploadPresignedSuccess, nil
+}
+
+// finalizeUploads checks the pending objects of the user and turns them into post images.
+// The pending objects are kept until the post is stored and removed by deletePendingUploads
+func (p PostService) finalizeUploads(ctx context.Context, userID string, keys []string) ([]models.PostImage, status.PostOperationStatus, error) {
+
+	images := make([]models.PostImage, 0, len(keys))
+
+	for _, key := range keys {
+		image, opStatus, err := p.finalizeUpload(ctx, userID, key)
+		if err != nil {
+			p.deleteImages(ctx, images)
+			return nil, opStatus, err
+		}
+		images = append(images, image)
+	}
+
+	return images, status.PostImagesUpdatedSuccess, nil
+}
+
+func (p PostService) finalizeUpload(ctx context.Context, userID string, key string) (models.PostImage, status.PostOperationStatus, error) {
+
+	if !isPendingUploadOf(key, userID) {
+		return models.PostImage{}, status.OperationForbidden, errors.New("upload " + key + " does not belong to the user")
+	}
+
+	info, err := p.StorageRepository.Stat(ctx, key)
+	if errors.Is(err, contracts.ErrUploadNotFound) {
+		return models.PostImage{}, status.PostUploadMissing, fmt.Errorf("%w: %s", err, key)
+	}
+	if err != nil {
+		return models.PostImage{}, status.PostCreatedStatusFailed, err
+	}
+
+	//Check the declared metadata before downloading anything
+	if err := p.StorageRepository.ValidateMeta(info.ContentType, info.Size); err != nil {
+		return models.PostImage{}, uploadErrorStatus(err, status.PostUploadInvalid), err
+	}
+
+	data, err := p.StorageRepository.Read(ctx, key)
+	if err != nil {
+		return models.PostImage{}, uploadErrorStatus(err, status.PostCreatedStatusFailed), err
+	}
+
+	image, err := p.storeImage(ctx, data)
+	if err != nil {
+		return models.PostImage{}, uploadErrorStatus(err, status.PostCreatedStatusFailed), err
+	}
+
+	return image, status.PostImagesUpdatedSuccess, nil
+}
+
+func (p PostService) deletePendingUploads(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := p.StorageRepository.Delete(ctx, key); err != nil {
+			log.Printf("Post Service: Delete Pending Upload >> %s: %v", key, err)
+		}
+	}
+}
+
+const presignedUploadTTL = 15 * time.Minute
+
+func pendingUploadPrefix(userID string) string {
+	return "uploads/" + userID
+}
+
+// isPendingUploadOf matches the keys generated by PresignUploads for the user
+func isPendingUploadOf(key string, userID string) bool {
+	pattern := `^\d{4}/\d{2}/` + regexp.QuoteMeta(pendingUploadPrefix(userID)) + `-[0-9a-f]{16}\.(jpg|png)$`
+	matched, _ := regexp.MatchString(pattern, key)
+	return matched
+}
+
+// uploadErrorStatus maps rejected uploads to their client error status
+func uploadErrorStatus(err error, fallback status.PostOperationStatus) status.PostOperationStatus {
+	switch {
+	case errors.Is(err, contracts.ErrUploadTooLarge), errors.Is(err, contracts.ErrImageTooLarge):
+		return status.PostUploadTooLarge
+	case errors.Is(err, contracts.ErrUploadNotFound):
+		return status.PostUploadMissing
+	case errors.Is(err, contracts.ErrUploadTypeNotAllowed),
+		errors.Is(err, contracts.ErrUploadCorrupted),
+		errors.Is(err, contracts.ErrUnsupportedImage):
+		return status.PostUploadInvalid
+	}
+	return fallback
+}
+
+func (p PostService) deleteImages(ctx context.Context, images []models.PostImage) {
+	for _, image := range images {
+		for _, key := range image.Keys() {
+			if err := p.StorageRepository.Delete(ctx, key); err != nil {
+				log.Printf("Post Service: Delete Image >> %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// purgeImages removes every stored image of the post, including a legacy single image.
+// Missing objects count as removed and a failing key doesn't stop the others, the first error is returned
+func (p PostService) purgeImages(ctx context.Context, post models.Post) error {
+
+	keys := make([]string, 0, 3*len(post.Images)+1)
+	for _, image := range post.Images {
+		keys = append(keys, image.Keys()...)
+	}
+	if len(post.Images) == 0 && post.ImageKey != "" {
+		keys = append(keys, post.ImageKey)
+	}
+
+	var purgeErr error
+	for _, key := range keys {
+		err := p.StorageRepository.Delete(ctx, key)
+		if err != nil && !errors.Is(err, contracts.ErrUploadNotFound) && purgeErr == nil {
+			purgeErr = err
+		}
+	}
+
+	return purgeErr
+}
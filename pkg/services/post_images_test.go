@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/http/requests"
+	"golek_posts_service/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// editedPost stores one post and applies conditional updates the way UpdateUnchanged does,
+// concurrentEdit changes the stored post between loading and saving it
+type editedPost struct {
+	contracts.PostRepositoryContract
+	post           models.Post
+	concurrentEdit func(post *models.Post)
+	audits         []models.PostAudit
+}
+
+func (e *editedPost) FindById(ctx context.Context, postID string) (models.Post, error) {
+	post := e.post
+	if e.concurrentEdit != nil {
+		e.concurrentEdit(&e.post)
+	}
+	return post, nil
+}
+
+func (e *editedPost) UpdateUnchanged(ctx context.Context, postID string, unchangedSince *time.Time, post models.Post, events ...models.OutboxEvent) (models.Post, status.PostOperationStatus, error) {
+	stored := e.post.UpdatedAt
+	if (stored == nil) != (unchangedSince == nil) || (stored != nil && !stored.Equal(*unchangedSince)) {
+		return models.Post{}, status.PostUpdatedStatusFailed, contracts.ErrPostStateConflict
+	}
+	e.post = post
+	e.audits = append(e.audits, contracts.AuditsOf(ctx)...)
+	return post, status.PostUpdatedStatusSuccess, nil
+}
+
+// deletedObjects records the keys removed from the storage
+type deletedObjects struct {
+	contracts.StorageRepository
+	keys []string
+}
+
+func (d *deletedObjects) Delete(ctx context.Context, key string) error {
+	d.keys = append(d.keys, key)
+	return nil
+}
+
+func TestPostImages(t *testing.T) {
+
+	t.Run("Arrange Images", func(t *testing.T) {
+		post := models.Post{
+			Images: []models.PostImage{
+				{ID: "a", URL: "url-a", Key: "key-a", SmallURL: "small-a"},
+				{ID: "b", URL: "url-b", Key: "key-b", SmallURL: "small-b"},
+			},
+			CoverImageID: "b",
+		}
+
+		post.ArrangeImages()
+		assert.Equal(t, "url-b", post.ImageURL)
+		assert.Equal(t, "key-b", post.ImageKey)
+		assert.Equal(t, "small-b", post.SmallImageURL)
+		assert.Equal(t, 1, post.Images[1].Order)
+
+		//Removing the cover falls back to the first image
+		post.Images = post.Images[:1]
+		post.ArrangeImages()
+		assert.Equal(t, "a", post.CoverImageID)
+		assert.Equal(t, "url-a", post.ImageURL)
+	})
+
+	t.Run("Pending Upload Ownership", func(t *testing.T) {
+		assert.True(t, isPendingUploadOf("2026/10/uploads/7-0123456789abcdef.jpg", "7"))
+		assert.False(t, isPendingUploadOf("2026/10/uploads/7-0123456789abcdef.jpg", "70"))
+		assert.False(t, isPendingUploadOf("2026/10/uploads/70-0123456789abcdef.jpg", "7"))
+		assert.False(t, isPendingUploadOf("2026/10/posts/abc-0123456789abcdef.jpg", "7"))
+		assert.False(t, isPendingUploadOf("../2026/10/uploads/7-0123456789abcdef.jpg", "7"))
+	})
+
+	ctx := context.WithValue(context.Background(), "authenticatedRequest",
+		&middleware.AuthenticatedRequest{UserID: "7", Role: "user", Permissions: "u"})
+
+	setup := func() (PostService, *editedPost, *deletedObjects) {
+		posts := &editedPost{post: models.Post{
+			ID:     primitive.NewObjectID(),
+			UserID: 7,
+			Images: []models.PostImage{{ID: "a", Key: "key-a"}, {ID: "b", Key: "key-b"}},
+		}}
+		storage := &deletedObjects{}
+		return PostService{PostRepository: posts, StorageRepository: storage}, posts, storage
+	}
+
+	//edit updates the post after it was loaded, as a concurrent request would
+	edit := func(post *models.Post) {
+		updatedAt := time.Now()
+		post.UpdatedAt = &updatedAt
+		post.Images = append(post.Images, models.PostImage{ID: "c", Key: "key-c"})
+	}
+
+	t.Run("Edits Unchanged Posts", func(t *testing.T) {
+		service, posts, _ := setup()
+
+		updated, opStatus, err := service.ReorderImages(ctx, posts.post.ID.Hex(), requests.ReorderPostImagesRequest{ImageIDs: []string{"b", "a"}})
+		assert.NoError(t, err)
+		assert.Equal(t, status.PostImagesUpdatedSuccess, opStatus)
+		assert.Equal(t, "b", updated.CoverImageID)
+
+		_, _, err = service.SetCoverImage(ctx, posts.post.ID.Hex(), "a")
+		assert.NoError(t, err)
+		assert.Equal(t, "a", posts.post.CoverImageID)
+
+		//Every edit is audited together with the update
+		assert.Len(t, posts.audits, 2)
+		assert.Equal(t, int64(7), posts.audits[1].ActorID)
+	})
+
+	t.Run("Reordering A Concurrently Edited Post Conflicts", func(t *testing.T) {
+		service, posts, _ := setup()
+		posts.concurrentEdit = edit
+
+		_, opStatus, err := service.ReorderImages(ctx, posts.post.ID.Hex(), requests.ReorderPostImagesRequest{ImageIDs: []string{"b", "a"}})
+		assert.ErrorIs(t, err, contracts.ErrPostStateConflict)
+		assert.Equal(t, status.PostImagesConflict, opStatus)
+		assert.Len(t, posts.post.Images, 3)
+		assert.Empty(t, posts.audits)
+	})
+
+	t.Run("Removing From A Concurrently Edited Post Keeps The Image", func(t *testing.T) {
+		service, posts, storage := setup()
+		posts.concurrentEdit = edit
+
+		_, opStatus, err := service.RemoveImage(ctx, posts.post.ID.Hex(), "a")
+		assert.ErrorIs(t, err, contracts.ErrPostStateConflict)
+		assert.Equal(t, status.PostImagesConflict, opStatus)
+		assert.Empty(t, storage.keys)
+	})
+
+	t.Run("Updating A Concurrently Edited Post Keeps Its Images", func(t *testing.T) {
+		service, posts, _ := setup()
+		posts.concurrentEdit = edit
+
+		_, opStatus, err := service.Update(ctx, posts.post.ID.Hex(), requests.UpdatePostRequest{Title: "Wallet", Place: "Library"})
+		assert.ErrorIs(t, err, contracts.ErrPostStateConflict)
+		assert.Equal(t, status.PostImagesConflict, opStatus)
+		assert.Len(t, posts.post.Images, 3)
+		assert.Empty(t, posts.post.Title)
+	})
+
+	t.Run("Removing An Image Deletes Its Objects", func(t *testing.T) {
+		service, posts, storage := setup()
+
+		updated, _, err := service.RemoveImage(ctx, posts.post.ID.Hex(), "a")
+		assert.NoError(t, err)
+		assert.Len(t, updated.Images, 1)
+		assert.Equal(t, []string{"key-a"}, storage.keys)
+	})
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/models"
+	"golek_posts_service/pkg/repositories"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// untouchedPosts fails the test when the post gets loaded
+type untouchedPosts struct {
+	contracts.PostRepositoryContract
+	t *testing.T
+}
+
+func (u untouchedPosts) FindById(ctx context.Context, postID string) (models.Post, error) {
+	u.t.Fatal("the post must not be loaded for invalid options")
+	return models.Post{}, nil
+}
+
+func TestRenderValidationQRCode(t *testing.T) {
+
+	t.Run("Reject Invalid Options Before Preparing The Validation", func(t *testing.T) {
+		service := PostService{
+			PostRepository:   untouchedPosts{t: t},
+			QrCodeRepository: repositories.NewQRCodeRepository(0, ""),
+		}
+
+		_, _, opStatus, err := service.RenderValidationQRCode(context.Background(), "post", contracts.QrCodeOptions{Size: 4096})
+		assert.ErrorIs(t, err, contracts.ErrInvalidQrCodeOptions)
+		assert.Equal(t, status.PostQRCodeOptionsInvalid, opStatus)
+
+		_, _, opStatus, err = service.RenderValidationQRCode(context.Background(), "post", contracts.QrCodeOptions{RecoveryLevel: "X"})
+		assert.ErrorIs(t, err, contracts.ErrInvalidQrCodeOptions)
+		assert.Equal(t, status.PostQRCodeOptionsInvalid, opStatus)
+	})
+}
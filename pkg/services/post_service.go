@@ -11,32 +11,50 @@ import (
 	"golek_posts_service/pkg/http/middleware"
 	"golek_posts_service/pkg/http/requests"
 	"golek_posts_service/pkg/models"
-	"golek_posts_service/pkg/utils"
 	"log"
-	"os"
-	"regexp"
+	"mime/multipart"
 	"strconv"
 	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type PostService struct {
-	StorageRepository   contracts.ICloudStorageRepo
-	PostRepository      contracts.PostRepositoryContract
-	QrCodeRepository    contracts.QrCodeRepository
-	MessageQueueService contracts.MessageQueue
+	StorageRepository      contracts.StorageRepository
+	PostRepository         contracts.PostRepositoryContract
+	QrCodeRepository       contracts.QrCodeRepository
+	OutboxRepository       contracts.OutboxRepositoryContract
+	ValidationTokenService contracts.ValidationTokenContract
+	MatchingService        contracts.MatchingServiceContract
+	ClaimRepository        contracts.ClaimRepositoryContract
+	AuditRepository        contracts.AuditRepositoryContract
+	ImageProcessor         contracts.ImageProcessorContract
+	MaxImages              int
 }
 
 func NewPostService(postRepository *contracts.PostRepositoryContract,
-	qrcodeRepository *contracts.QrCodeRepository, storageRepository *contracts.ICloudStorageRepo, mqService *contracts.MessageQueue) contracts.PostServiceContract {
+	qrcodeRepository *contracts.QrCodeRepository, storageRepository *contracts.StorageRepository, outboxRepository *contracts.OutboxRepositoryContract,
+	validationTokenService *contracts.ValidationTokenContract, matchingService *contracts.MatchingServiceContract,
+	claimRepository *contracts.ClaimRepositoryContract, auditRepository *contracts.AuditRepositoryContract,
+	imageProcessor *contracts.ImageProcessorContract, maxImages int) contracts.PostServiceContract {
+
+	if maxImages <= 0 {
+		maxImages = 5
+	}
 
 	return &PostService{
-		PostRepository:      *postRepository,
-		QrCodeRepository:    *qrcodeRepository,
-		StorageRepository:   *storageRepository,
-		MessageQueueService: *mqService,
+		PostRepository:         *postRepository,
+		QrCodeRepository:       *qrcodeRepository,
+		StorageRepository:      *storageRepository,
+		OutboxRepository:       *outboxRepository,
+		ValidationTokenService: *validationTokenService,
+		MatchingService:        *matchingService,
+		ClaimRepository:        *claimRepository,
+		AuditRepository:        *auditRepository,
+		ImageProcessor:         *imageProcessor,
+		MaxImages:              maxImages,
 	}
 }
 
@@ -48,17 +66,64 @@ func (p PostService) Search(ctx context.Context, keyword string, pagination mode
 		return []models.Post{}, err
 	}
 
-	return posts, nil
+	//The repository already skips trashed posts, this keeps them out of every other implementation too
+	found := make([]models.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.DeletedAt == nil {
+			found = append(found, post)
+		}
+	}
+
+	return found, nil
 }
 
 func (p PostService) RequestValidateOwner(ctx context.Context, postID string) (qrCode string, opStatus status.PostOperationStatus, err error) {
 
+	data, opStatus, err := p.prepareValidation(ctx, postID)
+	if err != nil || opStatus != status.PostRequestValidationSuccess {
+		return "", opStatus, err
+	}
+
+	//Generate qrcode with the validation data
+	qrcodeUrl, err := p.QrCodeRepository.Generate(data)
+	if err != nil {
+		return "", status.PostRequestValidationFailed, err
+	}
+
+	return qrcodeUrl, status.PostRequestValidationSuccess, nil
+}
+
+func (p PostService) RenderValidationQRCode(ctx context.Context, postID string, options contracts.QrCodeOptions) (image []byte, contentType string, opStatus status.PostOperationStatus, err error) {
+
+	//Reject the options before the confirmation key gets stored
+	options, err = p.QrCodeRepository.Validate(options)
+	if err != nil {
+		return nil, "", status.PostQRCodeOptionsInvalid, err
+	}
+
+	data, opStatus, err := p.prepareValidation(ctx, postID)
+	if err != nil || opStatus != status.PostRequestValidationSuccess {
+		return nil, "", opStatus, err
+	}
+
+	image, contentType, err = p.QrCodeRepository.Render(data, options)
+	if err != nil {
+		return nil, "", status.PostRequestValidationFailed, err
+	}
+
+	return image, contentType, status.PostRequestValidationSuccess, nil
+}
+
+// prepareValidation authorizes the request, stores the confirmation key on the post
+// and returns the data to be encoded into the validation QR code
+func (p PostService) prepareValidation(ctx context.Context, postID string) (data string, opStatus status.PostOperationStatus, err error) {
+
 	post, err := p.PostRepository.FindById(ctx, postID)
 	if err != nil {
 		return "", status.PostRequestValidationFailed, err
 	}
 
-	if post.IsReturned {
+	if post.CurrentStatus() == models.PostStatusReturned {
 		return "", status.PostAlreadyReturned, nil
 	}
 
@@ -73,10 +138,11 @@ func (p PostService) RequestValidateOwner(ctx context.Context, postID string) (q
 		authenticatedReq,
 		post,
 		func(isOwner bool) (opStatus status.PostOperationStatus, err error) {
-			//if !isOwner {
-			//	return status.OperationForbidden, errors.New(
-			//		fmt.Sprintf("User x is not the owner of Model y"))
-			//}
+			//Only the finder hands the item over, a claimant must not issue the token to itself
+			if !isOwner {
+				return status.OperationForbidden, errors.New(
+					fmt.Sprintf("User x is not the owner of Model y"))
+			}
 			return status.OperationAllowed, nil
 		},
 	)
@@ -85,28 +151,49 @@ func (p PostService) RequestValidateOwner(ctx context.Context, postID string) (q
 		return "", opStatus, err
 	}
 
-	//1. hash post id with hash secret
-	hash := utils.Hash(os.Getenv("VALIDATION_SECRET") + postID)
+	//Handover is only possible once the finder approved a claim
+	_, err = p.ClaimRepository.FindOne(ctx, map[string]any{
+		"post_id": post.ID,
+		"status":  models.ClaimStatusApproved,
+	})
+	if err == mongo.ErrNoDocuments {
+		return "", status.ClaimNotApproved, errors.New("post has no approved claim")
+	}
+	if err != nil {
+		return "", status.PostRequestValidationFailed, err
+	}
 
-	//2. create json string contains post id and hashed post id
-	data := fmt.Sprintf(`{"post_id": "%s", "hash": "%s"}`, postID, hash)
+	//1. Issue a signed, expiring token. Its nonce replaces any previously issued one
+	token, claims, err := p.ValidationTokenService.Issue(postID, authenticatedReq.UserID)
+	if err != nil {
+		return "", status.PostRequestValidationFailed, err
+	}
 
-	//3. generate qrcode with data from step 2
-	qrcodeUrl, err := p.QrCodeRepository.Generate(data)
+	//2. create json string contains post id and the token
+	payload, err := json.Marshal(map[string]string{"post_id": postID, "token": token})
 	if err != nil {
 		return "", status.PostRequestValidationFailed, err
 	}
+	data = string(payload)
 
-	//4. Assign hashed data to post
-	post.ConfirmationKey = hash
+	requested := post
+	requested.Status = models.PostStatusHandoverRequested
+	requestedEvent, err := postEvent(ctx, contracts.PostEventValidationRequested, post, requested)
+	if err != nil {
+		return "", status.PostRequestValidationFailed, err
+	}
 
-	//5. Update post
-	_, opStatus, err = p.PostRepository.Update(ctx, postID, post)
-	if err != nil || opStatus == status.PostUpdatedStatusFailed {
+	//3. Move the post to handover and store the token nonce on it
+	opStatus, err = transitionPost(ctx, p.PostRepository, post, models.PostStatusHandoverRequested, nil,
+		map[string]any{"confirmation_key": claims.Nonce}, requestedEvent)
+	if err != nil {
+		if opStatus == status.PostInvalidStateTransition {
+			return "", opStatus, err
+		}
 		return "", status.PostRequestValidationFailed, err
 	}
 
-	return qrcodeUrl, status.PostRequestValidationSuccess, nil
+	return data, status.PostRequestValidationSuccess, nil
 }
 
 func (p PostService) ValidateOwner(ctx context.Context, request requests.ValidateItemOwnerRequest) (opStatus status.PostOperationStatus, err error) {
@@ -116,7 +203,7 @@ func (p PostService) ValidateOwner(ctx context.Context, request requests.Validat
 		return status.PostValidateOwnerFailed, err
 	}
 
-	if post.IsReturned {
+	if post.CurrentStatus() == models.PostStatusReturned {
 		return status.PostAlreadyReturned, nil
 	}
 
@@ -143,16 +230,66 @@ func (p PostService) ValidateOwner(ctx context.Context, request requests.Validat
 		return opStatus, err
 	}
 
-	if !utils.HashCompare(request.Hash, os.Getenv("VALIDATION_SECRET")+request.PostID) && post.ConfirmationKey != request.Hash {
-		return status.PostValidateOwnerFailed, errors.New("unmatched given hash")
+	//Verify signature and expiry of the scanned token
+	claims, err := p.ValidationTokenService.Verify(request.Token)
+	if err != nil {
+		return validationTokenStatus(err), err
+	}
+
+	//The token is only valid when the finder issued it and someone else scans it
+	if claims.PostID != request.PostID || claims.IssuedBy != strconv.FormatInt(post.UserID, 10) ||
+		claims.IssuedBy == authenticatedReq.UserID {
+		return status.PostValidationTokenTampered, contracts.ErrValidationTokenTampered
+	}
+
+	//A token whose nonce is no longer stored was either used or superseded
+	if claims.Nonce != post.ConfirmationKey {
+		return status.PostValidationTokenReplayed, contracts.ErrValidationTokenReplayed
 	}
 
 	userID, _ := strconv.Atoi(authenticatedReq.UserID)
-	post.ReturnedTo = int64(userID)
-	post.IsReturned = true
 
-	_, opStatus, err2 := p.PostRepository.Update(ctx, request.PostID, post)
-	if err2 != nil || opStatus == status.PostUpdatedStatusFailed {
+	//Only a claimant approved by the finder can complete the handover
+	_, err = p.ClaimRepository.FindOne(ctx, map[string]any{
+		"post_id":     post.ID,
+		"claimant_id": int64(userID),
+		"status":      models.ClaimStatusApproved,
+	})
+	if err == mongo.ErrNoDocuments {
+		return status.ClaimNotApproved, errors.New("user has no approved claim on this post")
+	}
+	if err != nil {
+		return status.PostValidateOwnerFailed, err
+	}
+
+	returned := post
+	returned.Status = models.PostStatusReturned
+	returned.IsReturned = true
+	returned.ReturnedTo = int64(userID)
+
+	returnedEvent, err := postEvent(ctx, contracts.PostEventReturned, post, returned)
+	if err != nil {
+		return status.PostValidateOwnerFailed, err
+	}
+
+	auditCtx, err := audited(ctx, models.AuditOperationValidate, post, returned)
+	if err != nil {
+		return status.PostValidateOwnerFailed, err
+	}
+
+	//The nonce condition makes the token single-use even under concurrent scans
+	opStatus, err = transitionPost(auditCtx, p.PostRepository, post, models.PostStatusReturned,
+		map[string]any{"confirmation_key": claims.Nonce},
+		map[string]any{"is_returned": true, "returned_to": int64(userID), "confirmation_key": ""},
+		returnedEvent,
+	)
+	if err != nil {
+		if errors.Is(err, contracts.ErrPostStateConflict) {
+			return status.PostValidationTokenReplayed, contracts.ErrValidationTokenReplayed
+		}
+		if opStatus == status.PostInvalidStateTransition {
+			return opStatus, err
+		}
 		return status.PostValidateOwnerFailed, err
 	}
 
@@ -181,6 +318,26 @@ func (p PostService) FindById(ctx context.Context, postID string) (models.Post,
 	return post, nil
 }
 
+// FindByIDs returns the existing posts among postIDs, in the requested order
+func (p PostService) FindByIDs(ctx context.Context, postIDs []string) ([]models.Post, error) {
+	return p.PostRepository.FindByIDs(ctx, postIDs)
+}
+
+// Watch reports the changes of the posts matching the filter, resuming after resumeToken when given
+func (p PostService) Watch(ctx context.Context, filter contracts.PostWatchFilter, resumeToken string, fn func(change contracts.PostChange) error) error {
+	return p.PostRepository.Watch(ctx, filter, resumeToken, fn)
+}
+
+func (p PostService) FindMatches(ctx context.Context, postID string, limit int) ([]models.PostMatch, error) {
+
+	post, err := p.PostRepository.FindById(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.MatchingService.FindMatches(ctx, post, limit)
+}
+
 func (p PostService) Create(ctx context.Context, request requests.CreatePostRequest) (models.Post, status.PostOperationStatus, error) {
 
 	authenticatedReq := ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest)
@@ -211,12 +368,39 @@ func (p PostService) Create(ctx context.Context, request requests.CreatePostRequ
 		return models.Post{}, status.PostCreatedStatusFailed, err
 	}
 
+	postType := models.PostType(request.Type)
+	if postType == "" {
+		postType = models.PostTypeFound
+	}
+
 	timeNow := time.Now()
 
-	//Upload an image to Storage
-	fileUrl, err := p.StorageRepository.UploadFile(ctx, request.Image, request.Title+UnixMilliToStr())
+	//The single image field is kept for older clients and becomes the cover
+	files := request.Images
+	if request.Image != nil {
+		files = append([]*multipart.FileHeader{request.Image}, files...)
+	}
+
+	if count := len(files) + len(request.UploadKeys); count == 0 || count > p.MaxImages {
+		return models.Post{}, status.PostImagesInvalid, errors.New(
+			fmt.Sprintf("a post needs between 1 and %d images", p.MaxImages))
+	}
+
+	//Upload images to Storage
+	images, err := p.uploadImages(ctx, files)
 	if err != nil {
-		return models.Post{}, status.PostCreatedStatusFailed, err
+		return models.Post{}, uploadErrorStatus(err, status.PostCreatedStatusFailed), err
+	}
+
+	//Attach the images the client uploaded directly to the bucket
+	finalized, opStatus, err := p.finalizeUploads(ctx, authenticatedReq.UserID, request.UploadKeys)
+	if err != nil {
+		p.deleteImages(ctx, images)
+		return models.Post{}, opStatus, err
+	}
+	images = append(images, finalized...)
+	for i := range images {
+		images[i].Order = i
 	}
 
 	newPost := models.Post{
@@ -224,9 +408,10 @@ func (p PostService) Create(ctx context.Context, request requests.CreatePostRequ
 		UserID:          int64(userID),
 		ReturnedTo:      0,
 		IsReturned:      false,
+		Type:            postType,
+		Status:          models.PostStatusOpen,
 		Title:           request.Title,
-		ImageURL:        fileUrl,
-		ImageKey:        "",
+		Images:          images,
 		ConfirmationKey: "",
 		Place:           request.Place,
 		Description:     request.Description,
@@ -240,28 +425,50 @@ func (p PostService) Create(ctx context.Context, request requests.CreatePostRequ
 		},
 	}
 
-	createdPost, opStatus, err := p.PostRepository.Create(ctx, newPost)
+	newPost.ArrangeImages()
+
+	//Notify all User once the post is stored, the legacy notification is kept for the notification service
+	title := "Telah ditemukan " + newPost.Title
+	if newPost.Type == models.PostTypeLost {
+		title = "Telah hilang " + newPost.Title
+	}
+
+	payload, err := json.Marshal(contracts.MessagePayload{
+		UserID:   int64(userID),
+		Title:    title,
+		Body:     newPost.Characteristics[0].Title,
+		ImageUrl: newPost.ImageURL,
+	})
+	if err != nil {
+		p.deleteImages(ctx, images)
+		return models.Post{}, status.PostCreatedStatusFailed, err
+	}
+
+	createdEvent, err := postEvent(ctx, contracts.PostEventCreated, models.Post{}, newPost)
+	if err != nil {
+		p.deleteImages(ctx, images)
+		return models.Post{}, status.PostCreatedStatusFailed, err
+	}
+
+	auditCtx, err := audited(ctx, models.AuditOperationCreate, models.Post{}, newPost)
+	if err != nil {
+		p.deleteImages(ctx, images)
+		return models.Post{}, status.PostCreatedStatusFailed, err
+	}
+
+	createdPost, opStatus, err := p.PostRepository.Create(auditCtx, newPost,
+		models.NewOutboxEvent(newPost.ID, contracts.NewPostRoutingKey, payload), createdEvent)
 	if err != nil || opStatus == status.PostCreatedStatusFailed {
+		p.deleteImages(ctx, images)
 		return models.Post{}, status.PostCreatedStatusFailed, err
 	}
 
-	//Notify all User
-	go func() {
-		payload, err := json.Marshal(contracts.MessagePayload{
-			UserID:   int64(userID),
-			Title:    "Telah ditemukan " + createdPost.Title,
-			Body:     createdPost.Characteristics[0].Title,
-			ImageUrl: createdPost.ImageURL,
-		})
-		if err != nil {
-			log.Printf("Bookmark Service: Create >> %v", err)
-		}
+	p.deletePendingUploads(ctx, request.UploadKeys)
 
-		err = p.MessageQueueService.Publish(payload)
-		if err != nil {
-			log.Printf("Bookmark Service: Create >> Error Broadcast Message: %v", err)
-		}
-	}()
+	//Notify owners of lost posts which look like the found item
+	if createdPost.Type == models.PostTypeFound {
+		go p.notifyMatches(createdPost)
+	}
 
 	return createdPost, status.PostCreatedStatusSuccess, nil
 }
@@ -295,20 +502,39 @@ func (p PostService) Update(ctx context.Context, postID string, request requests
 		return models.Post{}, opStatus, err
 	}
 
+	opStatus, err = ensureEditable(post)
+	if err != nil {
+		return models.Post{}, opStatus, err
+	}
+
 	timeNow := time.Now()
+	before := post
 
 	//If authorized then,
-	//Delete old image and upload a new one (if image exists in update request)
+	//Upload a new cover image (if image exists in update request), the old one is removed after the update
+	var replacedImages, uploaded []models.PostImage
 	if request.Image != nil {
-		err := p.StorageRepository.DeleteFile(ctx, post.ImageURL)
+		uploaded, err = p.uploadImages(ctx, []*multipart.FileHeader{request.Image})
 		if err != nil {
-			return models.Post{}, status.PostUpdatedStatusFailed, err
+			return models.Post{}, uploadErrorStatus(err, status.PostUpdatedStatusFailed), err
 		}
 
-		post.ImageURL, err = p.StorageRepository.UploadFile(ctx, request.Image, request.Title+UnixMilliToStr())
-		if err != nil {
-			return models.Post{}, status.PostUpdatedStatusFailed, err
+		images := append([]models.PostImage(nil), post.Images...)
+		replaced := false
+		for i, image := range images {
+			if image.ID == post.CoverImageID {
+				replacedImages = append(replacedImages, image)
+				images[i] = uploaded[0]
+				replaced = true
+			}
 		}
+		if !replaced {
+			images = append(uploaded, images...)
+		}
+
+		post.Images = images
+		post.CoverImageID = uploaded[0].ID
+		post.ArrangeImages()
 	}
 
 	//Update post data
@@ -330,10 +556,28 @@ func (p PostService) Update(ctx context.Context, postID string, request requests
 		post.Characteristics = postCharacteristics
 	}
 
-	updatePost, opStatus, err := p.PostRepository.Update(ctx, postID, post)
+	updatedEvent, err := postEvent(ctx, contracts.PostEventUpdated, before, post)
+	if err != nil {
+		return models.Post{}, status.PostUpdatedStatusFailed, err
+	}
+
+	auditCtx, err := audited(ctx, models.AuditOperationUpdate, before, post)
+	if err != nil {
+		return models.Post{}, status.PostUpdatedStatusFailed, err
+	}
+
+	//The images are written back as loaded, so a concurrent image edit must not be overwritten
+	updatePost, opStatus, err := p.PostRepository.UpdateUnchanged(auditCtx, postID, before.UpdatedAt, post, updatedEvent)
 	if err != nil || opStatus == status.PostUpdatedStatusFailed {
+		p.deleteImages(ctx, uploaded)
+		if errors.Is(err, contracts.ErrPostStateConflict) {
+			return models.Post{}, status.PostImagesConflict, err
+		}
 		return models.Post{}, status.PostUpdatedStatusFailed, err
 	}
+
+	p.deleteImages(ctx, replacedImages)
+
 	return updatePost, status.PostUpdatedStatusSuccess, nil
 }
 
@@ -364,18 +608,260 @@ func (p PostService) Delete(ctx context.Context, postID string) (status.PostOper
 		return opStatus, err
 	}
 
-	err = p.StorageRepository.DeleteFile(ctx, post.ImageURL)
+	deleted := post
+	timeNow := time.Now()
+	deleted.DeletedAt = &timeNow
+
+	deletedEvent, err := postEvent(ctx, contracts.PostEventDeleted, post, deleted)
 	if err != nil {
 		return status.PostDeletedStatusFailed, err
 	}
 
-	opStatus, err = p.PostRepository.Delete(ctx, postID)
+	auditCtx, err := audited(ctx, models.AuditOperationDelete, post, deleted)
+	if err != nil {
+		return status.PostDeletedStatusFailed, err
+	}
+
+	//The image is kept until the post gets purged, so the post can be restored
+	opStatus, err = p.PostRepository.Delete(auditCtx, postID, deletedEvent)
 	if err != nil || opStatus == status.PostDeletedStatusFailed {
 		return status.PostDeletedStatusFailed, err
 	}
+
 	return status.PostDeletedStatusSuccess, nil
 }
 
+func (p PostService) Restore(ctx context.Context, postID string) (status.PostOperationStatus, error) {
+
+	post, err := p.PostRepository.FindTrashedById(ctx, postID)
+	if err != nil {
+		return status.PostRestoredStatusFailed, err
+	}
+
+	authenticatedReq := ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest)
+
+	opStatus, err := ProtectResource(
+		contracts.Resource{
+			Alias: "d",
+			Name:  "Delete",
+		},
+		authenticatedReq,
+		post,
+		func(isOwner bool) (opStatus status.PostOperationStatus, err error) {
+			if !isOwner && !IsAdmin(authenticatedReq) {
+				return status.OperationForbidden, errors.New(
+					fmt.Sprintf("User x is not the owner of Model y"))
+			}
+			return status.OperationAllowed, nil
+		},
+	)
+
+	if err != nil {
+		return opStatus, err
+	}
+
+	restored := post
+	restored.DeletedAt = nil
+
+	restoredEvent, err := postEvent(ctx, contracts.PostEventRestored, post, restored)
+	if err != nil {
+		return status.PostRestoredStatusFailed, err
+	}
+
+	auditCtx, err := audited(ctx, models.AuditOperationRestore, post, restored)
+	if err != nil {
+		return status.PostRestoredStatusFailed, err
+	}
+
+	opStatus, err = p.PostRepository.Restore(auditCtx, postID, restoredEvent)
+	if err != nil || opStatus == status.PostRestoredStatusFailed {
+		return status.PostRestoredStatusFailed, err
+	}
+
+	return status.PostRestoredStatusSuccess, nil
+}
+
+// PurgeDeleted permanently removes posts deleted longer than the retention period
+// together with their image and claims
+func (p PostService) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+
+	var purged int64
+
+	posts, err := p.PostRepository.FetchTrashed(ctx, time.Now().Add(-retention), 100)
+	if err != nil {
+		return purged, err
+	}
+
+	for _, post := range posts {
+
+		//The records go first, a post restored in the meantime keeps its images and claims
+		if _, err := p.PostRepository.Purge(ctx, post.ID.Hex()); err != nil {
+			log.Printf("Post Service: Purge >> post %s: %v", post.ID.Hex(), err)
+			continue
+		}
+		purged++
+
+		if _, err := p.ClaimRepository.DeleteByPost(ctx, post.ID.Hex()); err != nil {
+			log.Printf("Post Service: Purge >> post %s claims: %v", post.ID.Hex(), err)
+		}
+
+		//Images left behind are unreferenced now and get removed by the storage garbage collector
+		if err := p.purgeImages(ctx, post); err != nil {
+			log.Printf("Post Service: Purge >> post %s image: %v", post.ID.Hex(), err)
+		}
+	}
+
+	return purged, nil
+}
+
+// History returns the audit trail of a post, including deleted ones, to its owner and admins
+func (p PostService) History(ctx context.Context, postID string, pagination models.Pagination) ([]models.PostAudit, status.PostOperationStatus, error) {
+
+	post, err := p.PostRepository.FindById(ctx, postID)
+	if err == mongo.ErrNoDocuments {
+		post, err = p.PostRepository.FindTrashedById(ctx, postID)
+	}
+	if err != nil {
+		return nil, status.PostHistoryFetchFailed, err
+	}
+
+	authenticatedReq := ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest)
+
+	opStatus, err := ProtectResource(
+		contracts.Resource{
+			Alias: "u",
+			Name:  "History",
+		},
+		authenticatedReq,
+		post,
+		func(isOwner bool) (opStatus status.PostOperationStatus, err error) {
+			if !isOwner && !IsAdmin(authenticatedReq) {
+				return status.OperationForbidden, errors.New(
+					fmt.Sprintf("User x is not the owner of Model y"))
+			}
+			return status.OperationAllowed, nil
+		},
+	)
+
+	if err != nil {
+		return nil, opStatus, err
+	}
+
+	limit, skip := pagination.GetPagination()
+
+	audits, err := p.AuditRepository.FetchByPost(ctx, postID, limit, skip)
+	if err != nil {
+		return nil, status.PostHistoryFetchFailed, err
+	}
+
+	return audits, status.OperationAllowed, nil
+}
+
+func (p PostService) Archive(ctx context.Context, postID string) (status.PostOperationStatus, error) {
+
+	post, err := p.PostRepository.FindById(ctx, postID)
+	if err != nil {
+		return status.PostStatusUpdatedFailed, err
+	}
+
+	opStatus, err := ProtectResource(
+		contracts.Resource{
+			Alias: "u",
+			Name:  "Update",
+		},
+		ctx.Value("authenticatedRequest").(*middleware.AuthenticatedRequest),
+		post,
+		func(isOwner bool) (opStatus status.PostOperationStatus, err error) {
+			if !isOwner {
+				return status.OperationForbidden, errors.New(
+					fmt.Sprintf("User x is not the owner of Model y"))
+			}
+			return status.OperationAllowed, nil
+		},
+	)
+
+	if err != nil {
+		return opStatus, err
+	}
+
+	archivedEvent, err := statusChangedEvent(ctx, post, models.PostStatusArchived)
+	if err != nil {
+		return status.PostStatusUpdatedFailed, err
+	}
+
+	archived := post
+	archived.Status = models.PostStatusArchived
+	auditCtx, err := audited(ctx, models.AuditOperationArchive, post, archived)
+	if err != nil {
+		return status.PostStatusUpdatedFailed, err
+	}
+
+	opStatus, err = transitionPost(auditCtx, p.PostRepository, post, models.PostStatusArchived, nil,
+		map[string]any{"confirmation_key": ""}, archivedEvent)
+	if err != nil {
+		return opStatus, err
+	}
+
+	return status.PostStatusUpdatedSuccess, nil
+}
+
+// ExpireStale expires open posts which received no claim within maxAge
+func (p PostService) ExpireStale(ctx context.Context, maxAge time.Duration) (int64, error) {
+	return p.PostRepository.ExpireBefore(ctx, time.Now().Add(-maxAge), func(expired models.Post) (models.OutboxEvent, error) {
+		open := expired
+		open.Status = models.PostStatusOpen
+		return statusChangedEvent(ctx, open, models.PostStatusExpired)
+	})
+}
+
+func (p PostService) notifyMatches(post models.Post) {
+
+	matches, err := p.MatchingService.FindMatches(context.Background(), post, 10)
+	if err != nil {
+		log.Printf("Post Service: Matching >> %v", err)
+		return
+	}
+
+	events := make([]models.OutboxEvent, 0, len(matches))
+	for _, match := range matches {
+		payload, err := json.Marshal(contracts.MessagePayload{
+			UserID:   match.Post.UserID,
+			Title:    "Barang yang mirip dengan " + match.Post.Title + " telah ditemukan",
+			Body:     post.Title + " - " + post.Place,
+			ImageUrl: post.ImageURL,
+		})
+		if err != nil {
+			log.Printf("Post Service: Matching >> %v", err)
+			continue
+		}
+
+		events = append(events, models.NewOutboxEvent(post.ID, contracts.PostMatchRoutingKey, payload))
+	}
+
+	if err := p.OutboxRepository.Enqueue(context.Background(), events...); err != nil {
+		log.Printf("Post Service: Matching >> Error Broadcast Message: %v", err)
+	}
+}
+
+// validationTokenStatus maps token verification errors onto operation statuses
+func validationTokenStatus(err error) status.PostOperationStatus {
+	switch {
+	case errors.Is(err, contracts.ErrValidationTokenExpired):
+		return status.PostValidationTokenExpired
+	case errors.Is(err, contracts.ErrValidationTokenReplayed):
+		return status.PostValidationTokenReplayed
+	case errors.Is(err, contracts.ErrValidationTokenTampered):
+		return status.PostValidationTokenTampered
+	default:
+		return status.PostValidateOwnerFailed
+	}
+}
+
+// IsAdmin reports whether the request was made by an administrator
+func IsAdmin(authenticated *middleware.AuthenticatedRequest) bool {
+	return authenticated.Role == "admin"
+}
+
 // ProtectResource Test
 func ProtectResource(resource contracts.Resource, authenticated *middleware.AuthenticatedRequest, model models.Post, callback func(isOwner bool) (opStatus status.PostOperationStatus, err error)) (status.PostOperationStatus, error) {
 
@@ -402,11 +888,6 @@ func ProtectResource(resource contracts.Resource, authenticated *middleware.Auth
 	return opStatus, nil
 }
 
-func (p PostService) replaceVideoUrl(url string) string {
-	var regex, _ = regexp.Compile(os.Getenv("AWS_S3_DEFAULT_OBJECT_URL"))
-	return regex.ReplaceAllString(url, os.Getenv("AWS_CLOUDFRONT_URL"))
-}
-
 func UnixMilliToStr() string {
 	return strconv.Itoa(int(time.Now().UnixMilli()))
 }
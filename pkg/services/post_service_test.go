@@ -1,15 +1,20 @@
 package services
 
 import (
+	"bytes"
 	"context"
-	"golek_posts_service/cmd/msg_broker"
 	"golek_posts_service/pkg/contracts/status"
 	"golek_posts_service/pkg/database"
+	"golek_posts_service/pkg/http/middleware"
 	"golek_posts_service/pkg/http/requests"
 	"golek_posts_service/pkg/models"
 	"golek_posts_service/pkg/repositories"
+	"image"
+	"image/png"
+	"mime/multipart"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
@@ -24,46 +29,57 @@ func TestPostService(t *testing.T) {
 	}
 
 	db := database.Database{
-		DbName:       os.Getenv("DB_NAME"),
-		DbCollection: os.Getenv("DB_COLLECTION"),
-		DbHost:       os.Getenv("DB_HOST"),
-		DbPort:       os.Getenv("DB_PORT"),
-		DbUsername:   os.Getenv("DB_USERNAME"),
-		DBPassword:   os.Getenv("DB_PASSWORD"),
+		DbName:             os.Getenv("DB_NAME"),
+		DbCollection:       os.Getenv("DB_COLLECTION"),
+		DbClaimCollection:  "claims",
+		DbAuditCollection:  "post_audits",
+		DbOutboxCollection: "post_outbox",
+		DbHost:             os.Getenv("DB_HOST"),
+		DbPort:             os.Getenv("DB_PORT"),
+		DbUsername:         os.Getenv("DB_USERNAME"),
+		DBPassword:         os.Getenv("DB_PASSWORD"),
 	}
 	db.Prepare()
 
-	postRepository := repositories.NewPostRepository(db.GetConnection(), db.GetCollection())
-	qrcodeRepository := repositories.NewQRCodeRepository()
+	postRepository := repositories.NewPostRepository(db.GetConnection(), db.GetCollection(), db.GetOutboxCollection(), db.GetAuditCollection())
+	claimRepository := repositories.NewClaimRepository(db.GetClaimCollection())
+	auditRepository := repositories.NewAuditRepository(db.GetAuditCollection())
+	outboxRepository := repositories.NewOutboxRepository(db.GetOutboxCollection())
+	imageProcessor := NewImageProcessor(0, 0, 0, 0, 0)
+	qrcodeRepository := repositories.NewQRCodeRepository(256, "M")
 	awsS3Repository := repositories.NewS3Repository(
 		os.Getenv("AWS_ACCESS_KEY_ID"),
 		os.Getenv("AWS_SECRET_ACCESS_KEY"),
 		os.Getenv("AWS_BUCKET_NAME"),
 		os.Getenv("AWS_BUCKET_REGION"),
-		[]string{"image/jpeg", "image/png"},
-		int64(5*1024*1024), //MAX Filesize 5mb
+		repositories.NewUploadValidator([]string{"image/jpeg", "image/png"}, int64(5*1024*1024), 0), //MAX Filesize 5mb
+		0,
 		3,
 	)
+	if err := awsS3Repository.Connect(); err != nil {
+		t.Fatal(err)
+	}
 
-	//Establish Message Broker Connection
-	amqpConn := msg_broker.New(
-		os.Getenv("RABBITMQ_USER"),
-		os.Getenv("RABBITMQ_PASSWORD"),
-		os.Getenv("RABBITMQ_HOST"),
-		os.Getenv("RABBITMQ_PORT"),
-	)
-	mqPublisherService := msg_broker.NewMQPublisher(amqpConn)
-	mqPublisherService.Setup()
+	validationTokenService, err := NewValidationTokenService(os.Getenv("VALIDATION_SECRET"), 15*time.Minute)
+	if err != nil {
+		panic(err)
+	}
+
+	matchingService := NewMatchingService(&postRepository, 200, 0.2)
 
-	postService := NewPostService(&postRepository, &qrcodeRepository, &awsS3Repository, &mqPublisherService)
+	postService := NewPostService(&postRepository, &qrcodeRepository, &awsS3Repository, &outboxRepository, &validationTokenService, &matchingService, &claimRepository, &auditRepository, &imageProcessor, 5)
 
 	var createdPostID string
 
+	owner := context.WithValue(context.Background(), "authenticatedRequest",
+		&middleware.AuthenticatedRequest{UserID: "7", Role: "user", Permissions: "c,u,d,vp"})
+
 	t.Run("Create", func(t *testing.T) {
-		createdPost, opStatus, err := postService.Create(context.TODO(), requests.CreatePostRequest{
+		createdPost, opStatus, err := postService.Create(owner, requests.CreatePostRequest{
 
 			Title: "Samsung A35",
 			//ImageURL:           "https://randomwordgenerator.com/img/picture-generator/57e8dc4a4c57a914f1dc8460962e33791c3ad6e04e50744172287edc964dc6_640.jpg",
+			Image:           pngFile(t),
 			Place:           "Lantai 2 depan ruang dosen",
 			Description:     "",
 			Characteristics: []requests.PostCharacteristicRequest{{Title: "Casing hitam"}, {"Ada gantungan boneka"}},
@@ -79,24 +95,10 @@ func TestPostService(t *testing.T) {
 	})
 
 	t.Run("RequestValidateOwner", func(t *testing.T) {
-		qrcodeUrl, opStatus, err := postService.RequestValidateOwner(context.TODO(), createdPostID)
-		if err != nil || opStatus == status.PostRequestValidationSuccess {
-			t.Error(opStatus, err)
-		}
-
-		t.Log(qrcodeUrl)
-	})
-
-	t.Run("ValidateOwner", func(t *testing.T) {
-		opStatus, err := postService.ValidateOwner(context.TODO(), requests.ValidateItemOwnerRequest{
-			PostID: "636f342ce0ebfaa96cfef711",
-			Hash:   "$2a$10$VdTa0yoL2SPlUzyxpOkgcu5FFIx0IOhWt4au2WbmQ.NXhABZVExee",
-		})
-		if err != nil || opStatus != status.PostValidateOwnerSuccess {
-			t.Error(err)
-		}
-
-		assert.Truef(t, opStatus == status.PostValidateOwnerSuccess, "Validation Owner success, item has returned")
+		//The handover needs an approved claim first
+		_, opStatus, err := postService.RequestValidateOwner(owner, createdPostID)
+		assert.Error(t, err)
+		assert.Equal(t, status.ClaimNotApproved, opStatus)
 	})
 
 	t.Run("Search", func(t *testing.T) {
@@ -113,3 +115,23 @@ func TestPostService(t *testing.T) {
 	})
 
 }
+
+// pngFile returns a small png as uploaded by a multipart form
+func pngFile(t *testing.T) *multipart.FileHeader {
+
+	var data bytes.Buffer
+	assert.NoError(t, png.Encode(&data, image.NewRGBA(image.Rect(0, 0, 64, 64))))
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("image", "image.png")
+	assert.NoError(t, err)
+	_, err = part.Write(data.Bytes())
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	assert.NoError(t, err)
+
+	return form.File["image"][0]
+}
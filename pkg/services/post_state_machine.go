@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/models"
+)
+
+// postTransitions lists every status a post may move to from its current status
+var postTransitions = map[models.PostStatus][]models.PostStatus{
+	models.PostStatusOpen: {
+		models.PostStatusClaimPending,
+		models.PostStatusExpired,
+		models.PostStatusArchived,
+	},
+	models.PostStatusClaimPending: {
+		models.PostStatusOpen,
+		models.PostStatusHandoverRequested,
+		models.PostStatusExpired,
+		models.PostStatusArchived,
+	},
+	models.PostStatusHandoverRequested: {
+		//Requesting a new QR code re-issues the handover
+		models.PostStatusHandoverRequested,
+		models.PostStatusClaimPending,
+		models.PostStatusReturned,
+		models.PostStatusArchived,
+	},
+	models.PostStatusReturned: {
+		models.PostStatusArchived,
+	},
+	models.PostStatusExpired: {
+		models.PostStatusArchived,
+	},
+	models.PostStatusArchived: {},
+}
+
+func CanTransition(from models.PostStatus, to models.PostStatus) bool {
+	for _, allowed := range postTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether the post left the handover flow for good
+func IsTerminal(postStatus models.PostStatus) bool {
+	switch postStatus {
+	case models.PostStatusReturned, models.PostStatusExpired, models.PostStatusArchived:
+		return true
+	}
+	return false
+}
+
+// ensureEditable refuses content and image edits once the post reached a terminal status
+func ensureEditable(post models.Post) (status.PostOperationStatus, error) {
+	if IsTerminal(post.CurrentStatus()) {
+		return status.PostInvalidStateTransition, errors.New(
+			fmt.Sprintf("post in status %s can not be edited", post.CurrentStatus()))
+	}
+	return status.OperationAllowed, nil
+}
+
+// transitionPost moves the post to the given status when the state machine allows it.
+// The repository applies the change only if the stored status did not change meanwhile
+func transitionPost(ctx context.Context, postRepository contracts.PostRepositoryContract, post models.Post, to models.PostStatus, match map[string]any, set map[string]any, events ...models.OutboxEvent) (status.PostOperationStatus, error) {
+
+	from := post.CurrentStatus()
+
+	if !CanTransition(from, to) {
+		return status.PostInvalidStateTransition, errors.New(
+			fmt.Sprintf("post can not move from %s to %s", from, to))
+	}
+
+	return postRepository.Transition(ctx, post.ID.Hex(), from, to, match, set, events...)
+}
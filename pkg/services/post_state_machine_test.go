@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/http/requests"
+	"golek_posts_service/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPostStateMachine(t *testing.T) {
+
+	t.Run("Handover Flow", func(t *testing.T) {
+		assert.True(t, CanTransition(models.PostStatusOpen, models.PostStatusClaimPending))
+		assert.True(t, CanTransition(models.PostStatusClaimPending, models.PostStatusHandoverRequested))
+		assert.True(t, CanTransition(models.PostStatusHandoverRequested, models.PostStatusHandoverRequested))
+		assert.True(t, CanTransition(models.PostStatusHandoverRequested, models.PostStatusReturned))
+		assert.True(t, CanTransition(models.PostStatusReturned, models.PostStatusArchived))
+	})
+
+	t.Run("Rejected Transitions", func(t *testing.T) {
+		assert.False(t, CanTransition(models.PostStatusOpen, models.PostStatusReturned))
+		assert.False(t, CanTransition(models.PostStatusOpen, models.PostStatusHandoverRequested))
+		assert.False(t, CanTransition(models.PostStatusReturned, models.PostStatusOpen))
+		assert.False(t, CanTransition(models.PostStatusArchived, models.PostStatusOpen))
+		assert.False(t, CanTransition(models.PostStatusExpired, models.PostStatusClaimPending))
+	})
+
+	t.Run("Terminal Posts Are Not Editable", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), "authenticatedRequest",
+			&middleware.AuthenticatedRequest{UserID: "7", Role: "user", Permissions: "u"})
+
+		assert.False(t, IsTerminal(models.PostStatusHandoverRequested))
+
+		for _, postStatus := range []models.PostStatus{models.PostStatusReturned, models.PostStatusExpired, models.PostStatusArchived} {
+			assert.True(t, IsTerminal(postStatus))
+
+			posts := &editedPost{post: models.Post{
+				ID:     primitive.NewObjectID(),
+				UserID: 7,
+				Status: postStatus,
+				Images: []models.PostImage{{ID: "a", Key: "key-a"}, {ID: "b", Key: "key-b"}},
+			}}
+			service := PostService{PostRepository: posts, StorageRepository: &deletedObjects{}}
+
+			_, opStatus, err := service.Update(ctx, posts.post.ID.Hex(), requests.UpdatePostRequest{Title: "Wallet", Place: "Library"})
+			assert.Error(t, err)
+			assert.Equal(t, status.PostInvalidStateTransition, opStatus)
+
+			_, opStatus, err = service.ReorderImages(ctx, posts.post.ID.Hex(), requests.ReorderPostImagesRequest{ImageIDs: []string{"b", "a"}})
+			assert.Error(t, err)
+			assert.Equal(t, status.PostInvalidStateTransition, opStatus)
+
+			assert.Empty(t, posts.post.Title)
+			assert.Empty(t, posts.audits)
+		}
+	})
+
+	t.Run("Legacy Post Status", func(t *testing.T) {
+		assert.Equal(t, models.PostStatusOpen, models.Post{}.CurrentStatus())
+		assert.Equal(t, models.PostStatusReturned, models.Post{IsReturned: true}.CurrentStatus())
+	})
+}
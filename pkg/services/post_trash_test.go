@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// trashedPosts soft deletes, restores and purges the posts it holds like the posts collection
+type trashedPosts struct {
+	contracts.PostRepositoryContract
+	posts  map[string]*models.Post
+	events []models.OutboxEvent
+	audits []models.PostAudit
+	//purgeErr fails every purge, as a post restored in the meantime would
+	purgeErr error
+}
+
+func (t *trashedPosts) find(postID string, trashed bool) (models.Post, error) {
+	post, ok := t.posts[postID]
+	if !ok || (post.DeletedAt != nil) != trashed {
+		return models.Post{}, mongo.ErrNoDocuments
+	}
+	return *post, nil
+}
+
+func (t *trashedPosts) FindById(ctx context.Context, postID string) (models.Post, error) {
+	return t.find(postID, false)
+}
+
+func (t *trashedPosts) FindTrashedById(ctx context.Context, postID string) (models.Post, error) {
+	return t.find(postID, true)
+}
+
+func (t *trashedPosts) FetchTrashed(ctx context.Context, deletedBefore time.Time, limit int64) ([]models.Post, error) {
+	trashed := make([]models.Post, 0)
+	for _, post := range t.posts {
+		if post.DeletedAt != nil && post.DeletedAt.Before(deletedBefore) {
+			trashed = append(trashed, *post)
+		}
+	}
+	return trashed, nil
+}
+
+// Search matches every post holding the keyword in its title, trashed ones included
+func (t *trashedPosts) Search(ctx context.Context, keyword string, limit int64, skip int64) ([]models.Post, error) {
+	found := make([]models.Post, 0)
+	for _, post := range t.posts {
+		if strings.Contains(post.Title, keyword) {
+			found = append(found, *post)
+		}
+	}
+	return found, nil
+}
+
+func (t *trashedPosts) Delete(ctx context.Context, postID string, events ...models.OutboxEvent) (status.PostOperationStatus, error) {
+	deletedAt := time.Now()
+	t.posts[postID].DeletedAt = &deletedAt
+	t.events = append(t.events, events...)
+	t.audits = append(t.audits, contracts.AuditsOf(ctx)...)
+	return status.PostDeletedStatusSuccess, nil
+}
+
+func (t *trashedPosts) Restore(ctx context.Context, postID string, events ...models.OutboxEvent) (status.PostOperationStatus, error) {
+	t.posts[postID].DeletedAt = nil
+	t.events = append(t.events, events...)
+	t.audits = append(t.audits, contracts.AuditsOf(ctx)...)
+	return status.PostRestoredStatusSuccess, nil
+}
+
+func (t *trashedPosts) Purge(ctx context.Context, postID string) (status.PostOperationStatus, error) {
+	if t.purgeErr != nil {
+		return status.PostDeletedStatusFailed, t.purgeErr
+	}
+	delete(t.posts, postID)
+	return status.PostDeletedStatusSuccess, nil
+}
+
+// purgedClaims records the posts whose claims were deleted
+type purgedClaims struct {
+	contracts.ClaimRepositoryContract
+	postIDs []string
+}
+
+func (p *purgedClaims) DeleteByPost(ctx context.Context, postID string) (int64, error) {
+	p.postIDs = append(p.postIDs, postID)
+	return 1, nil
+}
+
+// flakyObjects fails deleting the keys in failing and records the others
+type flakyObjects struct {
+	contracts.StorageRepository
+	failing map[string]bool
+	deleted []string
+}
+
+func (f *flakyObjects) Delete(ctx context.Context, key string) error {
+	if f.failing[key] {
+		return errors.New("storage unavailable")
+	}
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+func TestPostTrash(t *testing.T) {
+
+	owner := context.WithValue(context.Background(), "authenticatedRequest",
+		&middleware.AuthenticatedRequest{UserID: "7", Role: "user", Permissions: "d"})
+
+	setup := func(deletedAt *time.Time) (PostService, *trashedPosts, *purgedClaims, *flakyObjects, models.Post) {
+		post := models.Post{
+			ID:        primitive.NewObjectID(),
+			UserID:    7,
+			Title:     "Black wallet",
+			Images:    []models.PostImage{{ID: "a", Key: "key-a", SmallKey: "key-a_small"}, {ID: "b", Key: "key-b"}},
+			DeletedAt: deletedAt,
+		}
+		posts := &trashedPosts{posts: map[string]*models.Post{post.ID.Hex(): &post}}
+		claims := &purgedClaims{}
+		storage := &flakyObjects{failing: map[string]bool{}}
+		return PostService{PostRepository: posts, ClaimRepository: claims, StorageRepository: storage}, posts, claims, storage, post
+	}
+
+	longAgo := time.Now().Add(-60 * 24 * time.Hour)
+
+	t.Run("Delete Keeps The Images", func(t *testing.T) {
+		service, posts, _, storage, post := setup(nil)
+
+		opStatus, err := service.Delete(owner, post.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, status.PostDeletedStatusSuccess, opStatus)
+		assert.NotNil(t, posts.posts[post.ID.Hex()].DeletedAt)
+		assert.Empty(t, storage.deleted)
+		assert.Equal(t, "post.deleted.v1", posts.events[0].RoutingKey)
+		assert.Equal(t, models.AuditOperationDelete, posts.audits[0].Operation)
+	})
+
+	t.Run("Search Skips Deleted Posts", func(t *testing.T) {
+		service, _, _, _, post := setup(nil)
+
+		found, err := service.Search(owner, "wallet", models.Pagination{Page: 1, PerPage: 25})
+		assert.NoError(t, err)
+		assert.Len(t, found, 1)
+
+		_, err = service.Delete(owner, post.ID.Hex())
+		assert.NoError(t, err)
+
+		found, err = service.Search(owner, "wallet", models.Pagination{Page: 1, PerPage: 25})
+		assert.NoError(t, err)
+		assert.Empty(t, found)
+	})
+
+	t.Run("Only The Owner Deletes", func(t *testing.T) {
+		service, posts, _, _, post := setup(nil)
+		other := context.WithValue(context.Background(), "authenticatedRequest",
+			&middleware.AuthenticatedRequest{UserID: "8", Role: "user", Permissions: "d"})
+
+		opStatus, err := service.Delete(other, post.ID.Hex())
+		assert.Error(t, err)
+		assert.Equal(t, status.OperationForbidden, opStatus)
+		assert.Nil(t, posts.posts[post.ID.Hex()].DeletedAt)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		service, posts, _, _, post := setup(&longAgo)
+
+		opStatus, err := service.Restore(owner, post.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, status.PostRestoredStatusSuccess, opStatus)
+		assert.Nil(t, posts.posts[post.ID.Hex()].DeletedAt)
+		assert.Equal(t, "post.restored.v1", posts.events[0].RoutingKey)
+		assert.Equal(t, models.AuditOperationRestore, posts.audits[0].Operation)
+
+		//A restored post is no longer in the trash
+		_, err = service.Restore(owner, post.ID.Hex())
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+	})
+
+	t.Run("Purge Removes Records, Claims And Images", func(t *testing.T) {
+		service, posts, claims, storage, post := setup(&longAgo)
+
+		purged, err := service.PurgeDeleted(context.Background(), 30*24*time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		assert.Empty(t, posts.posts)
+		assert.Equal(t, []string{post.ID.Hex()}, claims.postIDs)
+		assert.Equal(t, []string{"key-a", "key-a_small", "key-b"}, storage.deleted)
+	})
+
+	t.Run("Purge Keeps Images When The Record Stays", func(t *testing.T) {
+		service, posts, claims, storage, _ := setup(&longAgo)
+		posts.purgeErr = errors.New("unmatched any documents")
+
+		purged, err := service.PurgeDeleted(context.Background(), 30*24*time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), purged)
+		assert.Len(t, posts.posts, 1)
+		assert.Empty(t, claims.postIDs)
+		assert.Empty(t, storage.deleted)
+	})
+
+	t.Run("Purge Removes The Remaining Images When One Fails", func(t *testing.T) {
+		service, posts, _, storage, _ := setup(&longAgo)
+		storage.failing["key-a"] = true
+
+		purged, err := service.PurgeDeleted(context.Background(), 30*24*time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		assert.Empty(t, posts.posts)
+		assert.Equal(t, []string{"key-a_small", "key-b"}, storage.deleted)
+	})
+
+	t.Run("Purge Skips Recent Deletions", func(t *testing.T) {
+		recently := time.Now().Add(-time.Hour)
+		service, posts, _, _, _ := setup(&recently)
+
+		purged, err := service.PurgeDeleted(context.Background(), 30*24*time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), purged)
+		assert.Len(t, posts.posts, 1)
+	})
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/contracts/status"
+	"golek_posts_service/pkg/http/middleware"
+	"golek_posts_service/pkg/http/requests"
+	"golek_posts_service/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// handoverPost applies transitions to one post only while it still matches them, like the posts collection
+type handoverPost struct {
+	contracts.PostRepositoryContract
+	post models.Post
+}
+
+func (h *handoverPost) FindById(ctx context.Context, postID string) (models.Post, error) {
+	return h.post, nil
+}
+
+func (h *handoverPost) Transition(ctx context.Context, postID string, from models.PostStatus, to models.PostStatus, match map[string]any, set map[string]any, events ...models.OutboxEvent) (status.PostOperationStatus, error) {
+	if h.post.CurrentStatus() != from {
+		return status.PostStatusUpdatedFailed, contracts.ErrPostStateConflict
+	}
+	if key, ok := match["confirmation_key"]; ok && key != h.post.ConfirmationKey {
+		return status.PostStatusUpdatedFailed, contracts.ErrPostStateConflict
+	}
+	h.post.Status = to
+	if key, ok := set["confirmation_key"].(string); ok {
+		h.post.ConfirmationKey = key
+	}
+	if returnedTo, ok := set["returned_to"].(int64); ok {
+		h.post.ReturnedTo = returnedTo
+	}
+	return status.PostStatusUpdatedSuccess, nil
+}
+
+func TestPostValidation(t *testing.T) {
+
+	validationTokenService, err := NewValidationTokenService("validation-secret", 15*time.Minute)
+	assert.NoError(t, err)
+
+	userContext := func(userID string) context.Context {
+		return context.WithValue(context.Background(), "authenticatedRequest",
+			&middleware.AuthenticatedRequest{UserID: userID, Role: "user", Permissions: "vp"})
+	}
+	finder, claimant := userContext("7"), userContext("10")
+
+	setup := func() (PostService, *handoverPost) {
+		post := models.Post{ID: primitive.NewObjectID(), UserID: 7, Status: models.PostStatusClaimPending}
+		claim := models.Claim{ID: primitive.NewObjectID(), PostID: post.ID, ClaimantID: 10, Status: models.ClaimStatusApproved}
+		posts := &handoverPost{post: post}
+		claims := &memoryClaims{claims: map[primitive.ObjectID]*models.Claim{claim.ID: &claim}}
+		return PostService{PostRepository: posts, ClaimRepository: claims, ValidationTokenService: validationTokenService}, posts
+	}
+
+	t.Run("Only The Finder Issues The Token", func(t *testing.T) {
+		service, posts := setup()
+
+		_, opStatus, err := service.prepareValidation(claimant, posts.post.ID.Hex())
+		assert.Error(t, err)
+		assert.Equal(t, status.OperationForbidden, opStatus)
+		assert.Empty(t, posts.post.ConfirmationKey)
+
+		_, opStatus, err = service.prepareValidation(finder, posts.post.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, status.PostRequestValidationSuccess, opStatus)
+		assert.Equal(t, models.PostStatusHandoverRequested, posts.post.Status)
+		assert.NotEmpty(t, posts.post.ConfirmationKey)
+	})
+
+	t.Run("Claimant Completes The Handover Once", func(t *testing.T) {
+		service, posts := setup()
+		_, _, err := service.prepareValidation(finder, posts.post.ID.Hex())
+		assert.NoError(t, err)
+		token, _, err := validationTokenService.Issue(posts.post.ID.Hex(), "7")
+		assert.NoError(t, err)
+
+		//A token whose nonce was never stored is rejected
+		opStatus, err := service.ValidateOwner(claimant, requests.ValidateItemOwnerRequest{PostID: posts.post.ID.Hex(), Token: token})
+		assert.ErrorIs(t, err, contracts.ErrValidationTokenReplayed)
+		assert.Equal(t, status.PostValidationTokenReplayed, opStatus)
+
+		token, claims, err := validationTokenService.Issue(posts.post.ID.Hex(), "7")
+		assert.NoError(t, err)
+		posts.post.ConfirmationKey = claims.Nonce
+
+		opStatus, err = service.ValidateOwner(claimant, requests.ValidateItemOwnerRequest{PostID: posts.post.ID.Hex(), Token: token})
+		assert.NoError(t, err)
+		assert.Equal(t, status.PostValidateOwnerSuccess, opStatus)
+		assert.Equal(t, models.PostStatusReturned, posts.post.Status)
+		assert.Equal(t, int64(10), posts.post.ReturnedTo)
+
+		//The nonce was cleared, scanning again is a replay
+		posts.post.Status = models.PostStatusHandoverRequested
+		opStatus, err = service.ValidateOwner(claimant, requests.ValidateItemOwnerRequest{PostID: posts.post.ID.Hex(), Token: token})
+		assert.ErrorIs(t, err, contracts.ErrValidationTokenReplayed)
+		assert.Equal(t, status.PostValidationTokenReplayed, opStatus)
+	})
+
+	t.Run("Rejects Tokens Not Issued By The Finder", func(t *testing.T) {
+		service, posts := setup()
+
+		for issuer, scanner := range map[string]context.Context{
+			//Issued by someone else than the finder
+			"10": claimant,
+			//Scanned by the finder itself
+			"7": finder,
+		} {
+			token, claims, err := validationTokenService.Issue(posts.post.ID.Hex(), issuer)
+			assert.NoError(t, err)
+			posts.post.Status = models.PostStatusHandoverRequested
+			posts.post.ConfirmationKey = claims.Nonce
+
+			opStatus, err := service.ValidateOwner(scanner, requests.ValidateItemOwnerRequest{PostID: posts.post.ID.Hex(), Token: token})
+			assert.ErrorIs(t, err, contracts.ErrValidationTokenTampered)
+			assert.Equal(t, status.PostValidationTokenTampered, opStatus)
+			assert.Equal(t, models.PostStatusHandoverRequested, posts.post.Status)
+		}
+	})
+}
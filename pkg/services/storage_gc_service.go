@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/models"
+	"log"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// managedObjectKey matches the keys generated by ObjectName for images and pending uploads.
+// Anything else in the bucket was not written by this service and is never touched
+var managedObjectKey = regexp.MustCompile(`^\d{4}/\d{2}/`)
+
+// StorageGCService finds stored objects no post refers to and posts referring to missing objects
+type StorageGCService struct {
+	PostRepository    contracts.PostRepositoryContract
+	StorageRepository contracts.StorageRepository
+	minAge            time.Duration
+	now               func() time.Time
+}
+
+func (s StorageGCService) Reconcile(ctx context.Context, deleteOrphans bool) (contracts.StorageGCReport, error) {
+
+	report := contracts.StorageGCReport{
+		Orphans: make([]contracts.ObjectInfo, 0),
+		Missing: make([]contracts.MissingObject, 0),
+	}
+
+	//Every key referenced by a post, trashed posts keep their images until they are purged
+	referenced := map[string]string{}
+	err := s.PostRepository.EachImageReference(ctx, func(post models.Post) error {
+		for _, image := range post.Images {
+			for _, key := range image.Keys() {
+				referenced[key] = post.ID.Hex()
+			}
+		}
+		if post.ImageKey != "" {
+			referenced[post.ImageKey] = post.ID.Hex()
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	report.Referenced = len(referenced)
+
+	listed := map[string]bool{}
+	err = s.StorageRepository.List(ctx, "", func(object contracts.ObjectInfo) error {
+		report.Scanned++
+		listed[object.Key] = true
+
+		if !managedObjectKey.MatchString(object.Key) {
+			report.Unmanaged++
+			return nil
+		}
+
+		//Young objects may belong to a post which is being created right now
+		if _, ok := referenced[object.Key]; !ok && s.now().Sub(object.UpdatedAt) >= s.minAge {
+			report.Orphans = append(report.Orphans, object)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for key, postID := range referenced {
+		if !listed[key] {
+			report.Missing = append(report.Missing, contracts.MissingObject{PostID: postID, Key: key})
+		}
+	}
+	sort.Slice(report.Missing, func(i, j int) bool {
+		if report.Missing[i].PostID != report.Missing[j].PostID {
+			return report.Missing[i].PostID < report.Missing[j].PostID
+		}
+		return report.Missing[i].Key < report.Missing[j].Key
+	})
+
+	if deleteOrphans {
+		for _, orphan := range report.Orphans {
+			if err := s.StorageRepository.Delete(ctx, orphan.Key); err != nil {
+				log.Printf("Storage GC: Delete >> %s: %v", orphan.Key, err)
+				continue
+			}
+			report.Deleted++
+		}
+	}
+
+	return report, nil
+}
+
+func NewStorageGCService(postRepository *contracts.PostRepositoryContract, storageRepository *contracts.StorageRepository, minAge time.Duration) contracts.StorageGCContract {
+
+	if minAge <= 0 {
+		minAge = 24 * time.Hour
+	}
+
+	return &StorageGCService{
+		PostRepository:    *postRepository,
+		StorageRepository: *storageRepository,
+		minAge:            minAge,
+		now:               time.Now,
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/models"
+	"golek_posts_service/pkg/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// referencedPosts only implements the method used by the garbage collector
+type referencedPosts struct {
+	contracts.PostRepositoryContract
+	posts []models.Post
+}
+
+func (r referencedPosts) EachImageReference(ctx context.Context, fn func(post models.Post) error) error {
+	for _, post := range r.posts {
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestStorageGCService(t *testing.T) {
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	post := models.Post{
+		ID:       primitive.NewObjectID(),
+		ImageKey: "legacy-cover.jpg",
+		Images: []models.PostImage{
+			{ID: "a", Key: "2026/10/posts/a-1.jpg", SmallKey: "2026/10/posts/a_small-1.jpg"},
+		},
+	}
+
+	setup := func() (contracts.StorageRepository, contracts.StorageGCContract) {
+		storage := repositories.NewMemoryStorageService("bucket", repositories.NewUploadValidator(nil, 0, 0))
+		memory := storage.(*repositories.MemoryStorageService)
+		for key, updatedAt := range map[string]time.Time{
+			"2026/10/posts/a-1.jpg":         now.Add(-48 * time.Hour),
+			"2026/10/posts/orphan-1.jpg":    now.Add(-48 * time.Hour),
+			"2026/10/uploads/7-fresh-1.jpg": now.Add(-time.Minute),
+			"legacy-cover.jpg":              now.Add(-48 * time.Hour),
+			"someone-elses-file.txt":        now.Add(-48 * time.Hour),
+		} {
+			memory.Put(key, []byte("data"))
+			memory.SetUpdatedAt(key, updatedAt)
+		}
+
+		var postRepository contracts.PostRepositoryContract = referencedPosts{posts: []models.Post{post}}
+		gc := NewStorageGCService(&postRepository, &storage, 24*time.Hour)
+		gc.(*StorageGCService).now = func() time.Time { return now }
+		return storage, gc
+	}
+
+	t.Run("Reports Orphans And Missing Objects", func(t *testing.T) {
+		storage, gc := setup()
+
+		report, err := gc.Reconcile(context.Background(), false)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, report.Scanned)
+		assert.Equal(t, 2, report.Unmanaged)
+		assert.Equal(t, 3, report.Referenced)
+		assert.Equal(t, 0, report.Deleted)
+
+		assert.Len(t, report.Orphans, 1)
+		assert.Equal(t, "2026/10/posts/orphan-1.jpg", report.Orphans[0].Key)
+
+		assert.Equal(t, []contracts.MissingObject{{PostID: post.ID.Hex(), Key: "2026/10/posts/a_small-1.jpg"}}, report.Missing)
+
+		_, err = storage.Stat(context.Background(), "2026/10/posts/orphan-1.jpg")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Deletes Orphans", func(t *testing.T) {
+		storage, gc := setup()
+
+		report, err := gc.Reconcile(context.Background(), true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Deleted)
+
+		_, err = storage.Stat(context.Background(), "2026/10/posts/orphan-1.jpg")
+		assert.ErrorIs(t, err, contracts.ErrUploadNotFound)
+
+		//Referenced, young and foreign objects are kept
+		for _, key := range []string{"2026/10/posts/a-1.jpg", "2026/10/uploads/7-fresh-1.jpg", "legacy-cover.jpg", "someone-elses-file.txt"} {
+			_, err = storage.Stat(context.Background(), key)
+			assert.NoError(t, err, key)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/models"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// URLResolver serves stored images from a CDN. Without a base url the urls stored by
+// the storage backend are kept. With a signing key every url is a Cloud CDN signed url,
+// carrying Expires, KeyName and an HMAC-SHA1 Signature verified by the CDN edge
+type URLResolver struct {
+	baseURL    string
+	keyName    string
+	signingKey []byte
+	ttl        time.Duration
+	now        func() time.Time
+}
+
+func (u URLResolver) Resolve(key string, fallback string) string {
+
+	if u.baseURL == "" || key == "" {
+		return fallback
+	}
+
+	path := "/" + (&url.URL{Path: key}).EscapedPath()
+	if u.keyName == "" || len(u.signingKey) == 0 {
+		return u.baseURL + path
+	}
+
+	//Expiries are aligned to the ttl so the same url is handed out for a while and stays cacheable
+	window := int64(u.ttl / time.Second)
+	expires := strconv.FormatInt((u.now().Unix()/window+2)*window, 10)
+
+	//The signature covers the whole url up to and including the KeyName parameter
+	signed := u.baseURL + path + "?Expires=" + expires + "&KeyName=" + url.QueryEscape(u.keyName)
+	mac := hmac.New(sha1.New, u.signingKey)
+	mac.Write([]byte(signed))
+
+	return signed + "&Signature=" + base64.URLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (u URLResolver) ResolvePost(post models.Post) models.Post {
+
+	images := make([]models.PostImage, len(post.Images))
+	for i, image := range post.Images {
+		image.URL = u.Resolve(image.Key, image.URL)
+		image.MediumURL = u.Resolve(image.MediumKey, image.MediumURL)
+		image.SmallURL = u.Resolve(image.SmallKey, image.SmallURL)
+		images[i] = image
+	}
+	if post.Images == nil {
+		images = nil
+	}
+
+	post.Images = images
+	post.ArrangeImages()
+
+	//Posts stored before the gallery only know their single image
+	if len(post.Images) == 0 {
+		post.ImageURL = u.Resolve(post.ImageKey, post.ImageURL)
+	}
+
+	return post
+}
+
+// NewURLResolver takes the decoded key of the Cloud CDN signing key named keyName
+func NewURLResolver(baseURL string, keyName string, signingKey []byte, ttl time.Duration) contracts.URLResolverContract {
+
+	//Expiries are whole seconds, a shorter ttl has no window to align to
+	if ttl <= 0 {
+		ttl = time.Hour
+	} else if ttl < time.Second {
+		ttl = time.Second
+	}
+
+	return &URLResolver{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		keyName:    keyName,
+		signingKey: signingKey,
+		ttl:        ttl,
+		now:        time.Now,
+	}
+}
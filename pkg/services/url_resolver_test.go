@@ -0,0 +1,67 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"golek_posts_service/pkg/models"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLResolver(t *testing.T) {
+
+	post := models.Post{
+		Images: []models.PostImage{
+			{ID: "a", Key: "posts/a.jpg", URL: "https://storage.googleapis.com/bucket/posts/a.jpg", SmallKey: "posts/a_small.jpg"},
+		},
+	}
+
+	t.Run("Keeps Stored Urls Without Cdn", func(t *testing.T) {
+		resolved := NewURLResolver("", "", nil, 0).ResolvePost(post)
+		assert.Equal(t, post.Images[0].URL, resolved.ImageURL)
+	})
+
+	t.Run("Rewrites Keys To The Cdn", func(t *testing.T) {
+		resolved := NewURLResolver("https://cdn.example.com/", "", nil, 0).ResolvePost(post)
+		assert.Equal(t, "https://cdn.example.com/posts/a.jpg", resolved.Images[0].URL)
+		assert.Equal(t, "https://cdn.example.com/posts/a_small.jpg", resolved.SmallImageURL)
+		assert.Equal(t, "https://cdn.example.com/posts/a.jpg", resolved.ImageURL)
+
+		//The stored post is left untouched
+		assert.Equal(t, "https://storage.googleapis.com/bucket/posts/a.jpg", post.Images[0].URL)
+	})
+
+	t.Run("Signed Urls", func(t *testing.T) {
+		now := time.Unix(1_700_000_000, 0)
+		key, _ := base64.URLEncoding.DecodeString("nZtRohdNF9m3cKM24IcK4w==")
+		resolver := URLResolver{baseURL: "https://cdn.example.com", keyName: "golek-key", signingKey: key, ttl: time.Hour, now: func() time.Time { return now }}
+
+		first := resolver.Resolve("posts/a.jpg", "")
+		signed, err := url.Parse(first)
+		assert.NoError(t, err)
+		assert.Equal(t, "/posts/a.jpg", signed.Path)
+		assert.Equal(t, "1700006400", signed.Query().Get("Expires"))
+		assert.Equal(t, "golek-key", signed.Query().Get("KeyName"))
+
+		//Cloud CDN signs everything before &Signature= with HMAC-SHA1 of the decoded key
+		mac := hmac.New(sha1.New, key)
+		mac.Write([]byte(first[:strings.Index(first, "&Signature=")]))
+		assert.Equal(t, base64.URLEncoding.EncodeToString(mac.Sum(nil)), signed.Query().Get("Signature"))
+
+		//Urls stay stable within one window and change afterwards
+		now = now.Add(time.Minute)
+		assert.Equal(t, first, resolver.Resolve("posts/a.jpg", ""))
+		now = now.Add(2 * time.Hour)
+		assert.NotEqual(t, first, resolver.Resolve("posts/a.jpg", ""))
+	})
+
+	t.Run("Sub Second Ttl Does Not Panic", func(t *testing.T) {
+		resolver := NewURLResolver("https://cdn.example.com", "golek-key", []byte("secret"), time.Millisecond)
+		assert.NotPanics(t, func() { resolver.Resolve("posts/a.jpg", "") })
+	})
+}
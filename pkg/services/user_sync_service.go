@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/models"
+	"log"
+)
+
+// UserSyncService keeps the user copied into posts in line with the auth service
+type UserSyncService struct {
+	PostRepository contracts.PostRepositoryContract
+}
+
+func (u UserSyncService) HandleUserEvent(ctx context.Context, event contracts.UserEvent) error {
+
+	if event.User.ID <= 0 || event.OccurredAt.IsZero() {
+		return fmt.Errorf("%w: event %q has no user or time", contracts.ErrInvalidUserEvent, event.ID)
+	}
+
+	var user models.UserInfo
+	switch event.Type {
+	case contracts.UserEventUpdated:
+		user = models.UserInfo{Username: event.User.Username, UserMajor: event.User.UserMajor}
+	case contracts.UserEventDeleted:
+		user = models.UserInfo{Username: models.AnonymousUsername}
+	default:
+		return fmt.Errorf("%w: unknown type %q", contracts.ErrInvalidUserEvent, event.Type)
+	}
+
+	synced, err := u.PostRepository.SyncUserInfo(ctx, event.User.ID, user, event.OccurredAt)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("User Sync: %s for user %d updated %d posts", event.Type, event.User.ID, synced)
+
+	return nil
+}
+
+func NewUserSyncService(postRepository *contracts.PostRepositoryContract) contracts.UserSyncContract {
+	return &UserSyncService{PostRepository: *postRepository}
+}
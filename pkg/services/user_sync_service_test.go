@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"golek_posts_service/pkg/contracts"
+	"golek_posts_service/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// syncedUsers only implements the method used by the user sync
+type syncedUsers struct {
+	contracts.PostRepositoryContract
+	users    map[int64]models.UserInfo
+	syncedAt map[int64]time.Time
+}
+
+func (s *syncedUsers) SyncUserInfo(ctx context.Context, userID int64, user models.UserInfo, syncedAt time.Time) (int64, error) {
+	if last, ok := s.syncedAt[userID]; ok && !last.Before(syncedAt) {
+		return 0, nil
+	}
+	s.users[userID] = user
+	s.syncedAt[userID] = syncedAt
+	return 1, nil
+}
+
+func TestUserSyncService(t *testing.T) {
+
+	repository := &syncedUsers{users: map[int64]models.UserInfo{}, syncedAt: map[int64]time.Time{}}
+	var postRepository contracts.PostRepositoryContract = repository
+	userSync := NewUserSyncService(&postRepository)
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	event := func(eventType contracts.UserEventType, occurredAt time.Time, username string) contracts.UserEvent {
+		e := contracts.UserEvent{ID: username, Type: eventType, OccurredAt: occurredAt}
+		e.User.ID = 7
+		e.User.Username = username
+		e.User.UserMajor = "Informatika"
+		return e
+	}
+
+	t.Run("Applies Updates In Order", func(t *testing.T) {
+		assert.NoError(t, userSync.HandleUserEvent(context.Background(), event(contracts.UserEventUpdated, now, "budi")))
+		assert.Equal(t, models.UserInfo{Username: "budi", UserMajor: "Informatika"}, repository.users[7])
+
+		//A replayed or older event changes nothing
+		assert.NoError(t, userSync.HandleUserEvent(context.Background(), event(contracts.UserEventUpdated, now.Add(-time.Hour), "old")))
+		assert.Equal(t, "budi", repository.users[7].Username)
+	})
+
+	t.Run("Anonymizes Deleted Users", func(t *testing.T) {
+		assert.NoError(t, userSync.HandleUserEvent(context.Background(), event(contracts.UserEventDeleted, now.Add(time.Hour), "budi")))
+		assert.Equal(t, models.UserInfo{Username: models.AnonymousUsername}, repository.users[7])
+	})
+
+	t.Run("Rejects Invalid Events", func(t *testing.T) {
+		err := userSync.HandleUserEvent(context.Background(), event("user.renamed", now, "x"))
+		assert.ErrorIs(t, err, contracts.ErrInvalidUserEvent)
+
+		err = userSync.HandleUserEvent(context.Background(), contracts.UserEvent{Type: contracts.UserEventUpdated, OccurredAt: now})
+		assert.ErrorIs(t, err, contracts.ErrInvalidUserEvent)
+	})
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"golek_posts_service/pkg/contracts"
+	"strings"
+	"time"
+)
+
+// ValidationTokenService issues HMAC-SHA256 signed ownership validation tokens.
+// A token has the form base64url(claims) "." base64url(signature)
+type ValidationTokenService struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func (v ValidationTokenService) Issue(postID string, issuedBy string) (token string, claims contracts.ValidationClaims, err error) {
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", contracts.ValidationClaims{}, err
+	}
+
+	claims = contracts.ValidationClaims{
+		PostID:    postID,
+		IssuedBy:  issuedBy,
+		Nonce:     hex.EncodeToString(nonce),
+		ExpiresAt: v.now().Add(v.ttl).Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", contracts.ValidationClaims{}, err
+	}
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encodedPayload + "." + v.sign(encodedPayload), claims, nil
+}
+
+func (v ValidationTokenService) Verify(token string) (contracts.ValidationClaims, error) {
+
+	var claims contracts.ValidationClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return claims, contracts.ErrValidationTokenTampered
+	}
+
+	//Compare signatures in constant time before trusting the payload
+	if !hmac.Equal([]byte(parts[1]), []byte(v.sign(parts[0]))) {
+		return claims, contracts.ErrValidationTokenTampered
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return claims, contracts.ErrValidationTokenTampered
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, contracts.ErrValidationTokenTampered
+	}
+
+	if v.now().Unix() >= claims.ExpiresAt {
+		return claims, contracts.ErrValidationTokenExpired
+	}
+
+	return claims, nil
+}
+
+func (v ValidationTokenService) sign(encodedPayload string) string {
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(encodedPayload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func NewValidationTokenService(secret string, ttl time.Duration) (contracts.ValidationTokenContract, error) {
+
+	if secret == "" {
+		return nil, errors.New("validation token secret must not be empty")
+	}
+
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+
+	return &ValidationTokenService{secret: []byte(secret), ttl: ttl, now: time.Now}, nil
+}
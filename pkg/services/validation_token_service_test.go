@@ -0,0 +1,66 @@
+package services
+
+import (
+	"golek_posts_service/pkg/contracts"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationTokenService(t *testing.T) {
+
+	tokenService := &ValidationTokenService{secret: []byte("secret"), ttl: time.Minute, now: time.Now}
+
+	t.Run("Issue and Verify", func(t *testing.T) {
+		token, issued, err := tokenService.Issue("636f342ce0ebfaa96cfef711", "100")
+		if err != nil {
+			t.Error(err)
+		}
+
+		claims, err := tokenService.Verify(token)
+		assert.NoError(t, err)
+		assert.Equal(t, issued, claims)
+		assert.NotEmpty(t, claims.Nonce)
+	})
+
+	t.Run("Unique Nonce", func(t *testing.T) {
+		_, first, _ := tokenService.Issue("636f342ce0ebfaa96cfef711", "100")
+		_, second, _ := tokenService.Issue("636f342ce0ebfaa96cfef711", "100")
+		assert.NotEqual(t, first.Nonce, second.Nonce)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		token, _, _ := tokenService.Issue("636f342ce0ebfaa96cfef711", "100")
+
+		later := &ValidationTokenService{secret: tokenService.secret, ttl: time.Minute, now: func() time.Time {
+			return time.Now().Add(2 * time.Minute)
+		}}
+
+		_, err := later.Verify(token)
+		assert.ErrorIs(t, err, contracts.ErrValidationTokenExpired)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		token, _, _ := tokenService.Issue("636f342ce0ebfaa96cfef711", "100")
+		forged, _, _ := tokenService.Issue("636f342ce0ebfaa96cfef799", "100")
+
+		//Swap the payload while keeping the original signature
+		tampered := strings.Split(forged, ".")[0] + "." + strings.Split(token, ".")[1]
+
+		_, err := tokenService.Verify(tampered)
+		assert.ErrorIs(t, err, contracts.ErrValidationTokenTampered)
+
+		_, err = tokenService.Verify("not-a-token")
+		assert.ErrorIs(t, err, contracts.ErrValidationTokenTampered)
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		token, _, _ := tokenService.Issue("636f342ce0ebfaa96cfef711", "100")
+		other := &ValidationTokenService{secret: []byte("other"), ttl: time.Minute, now: time.Now}
+
+		_, err := other.Verify(token)
+		assert.ErrorIs(t, err, contracts.ErrValidationTokenTampered)
+	})
+}